@@ -1,111 +1,169 @@
 package dfmgr
 
 import (
-	"log"
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	cfg "github.com/lidstromberg/config"
 
 	"golang.org/x/net/context"
+	"gopkg.in/yaml.v3"
 )
 
-var (
-	//EnvDebugOn controls verbose logging
-	EnvDebugOn bool
-)
+//EnvConfigFile is the environment variable naming an optional json or yaml config file
+const EnvConfigFile = "DF_CONFIG_FILE"
 
-//preflight checks that the incoming configuration map contains the required config elements
-func preflight(ctx context.Context, bc cfg.ConfigSetting) {
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.LUTC)
-	log.Println("Started DfMgr preflight..")
+//configVar maps a config setting to the environment variable and config file key it is loaded from
+type configVar struct {
+	setting  string
+	variable string
+	key      string
+	//sql is set for the settings which are only required by the postgres job store
+	sql bool
+}
 
-	cfm1 := preflightConfigLoader()
-	bc.LoadConfigMap(ctx, cfm1)
+//configVars are the settings required by the package, in the order they are validated
+var configVars = []configVar{
+	/**********************************************************************
+	* DATAFLOW ENV SETTINGS
+	**********************************************************************/
+	//EnvDebugOn is the debug setting
+	{"EnvDebugOn", "DF_DEBUGON", "debugon", false},
+	//EnvDfGcpProject is the project setting
+	{"EnvDfGcpProject", "DF_GCP_PROJECT", "gcpproject", false},
+	//EnvDfGcpRegion is the region setting
+	{"EnvDfGcpRegion", "DF_GCP_REGION", "gcpregion", false},
+	//EnvDfParamsBucket is the GCS bucket storing the parameter files
+	{"EnvDfParamsBucket", "DF_BUCKET", "bucket", false},
 
-	if bc.GetConfigValue(ctx, "EnvDebugOn") == "" {
-		log.Fatal("Could not parse environment variable EnvDebugOn")
-	}
+	/**********************************************************************
+	* SQL ENV SETTINGS
+	**********************************************************************/
+	//EnvSqlDst is the sql driver name
+	{"EnvSqlDst", "DF_SQLDST", "sqldst", true},
+	//EnvSqlConnection is the sql connection string
+	{"EnvSqlConnection", "DF_SQLCNX", "sqlcnx", true},
+}
 
-	if bc.GetConfigValue(ctx, "EnvDfGcpProject") == "" {
-		log.Fatal("Could not parse environment variable EnvDfGcpProject")
-	}
+//preflight checks that the incoming configuration map contains the required config elements, returning the debug setting
+//or a *ConfigError listing every problem found. The sql settings are only checked if withSQL is set.
+func preflight(ctx context.Context, bc cfg.ConfigSetting, withSQL bool) (bool, error) {
+	cerr := &ConfigError{}
 
-	if bc.GetConfigValue(ctx, "EnvDfGcpRegion") == "" {
-		log.Fatal("Could not parse environment variable EnvDfGcpRegion")
+	cfm1, err := preflightConfigLoader()
+	if err != nil {
+		cerr.add(configVar{setting: "EnvConfigFile", variable: EnvConfigFile}, "could not be loaded: "+err.Error())
 	}
 
-	if bc.GetConfigValue(ctx, "EnvDfParamsBucket") == "" {
-		log.Fatal("Could not parse environment variable EnvDfParamsBucket")
-	}
+	bc.LoadConfigMap(ctx, cfm1)
 
-	if bc.GetConfigValue(ctx, "EnvSqlConnection") == "" {
-		log.Fatal("Could not parse environment variable EnvSqlConnection")
-	}
+	for _, item := range configVars {
+		if item.sql && !withSQL {
+			continue
+		}
 
-	if bc.GetConfigValue(ctx, "EnvSqlDst") == "" {
-		log.Fatal("Could not parse environment variable EnvSqlDst")
+		if bc.GetConfigValue(ctx, item.setting) == "" {
+			cerr.add(item, "is not set")
+		}
 	}
 
-	//set the debug value
-	constlog, err := strconv.ParseBool(bc.GetConfigValue(ctx, "EnvDebugOn"))
-
-	if err != nil {
-		log.Fatal("Could not parse environment variable EnvDebugOn")
+	//get the debug value
+	var constlog bool
+	if val := bc.GetConfigValue(ctx, "EnvDebugOn"); val != "" {
+		constlog, err = strconv.ParseBool(val)
+		if err != nil {
+			cerr.add(configVars[0], "is not a valid boolean")
+		}
 	}
 
-	EnvDebugOn = constlog
+	if len(cerr.Problems) > 0 {
+		return false, cerr
+	}
 
-	log.Println("..Finished DfMgr preflight.")
+	return constlog, nil
 }
 
 //preflightConfigLoader loads the session config vars
-func preflightConfigLoader() map[string]string {
+//
+//Settings are applied in increasing order of precedence:
+//  1. values already held in the ConfigSetting
+//  2. values from the json/yaml file named by DF_CONFIG_FILE (if set)
+//  3. values from the DF_* environment variables (if set)
+func preflightConfigLoader() (map[string]string, error) {
 	cfm := make(map[string]string)
 
-	/**********************************************************************
-	* DATAFLOW ENV SETTINGS
-	**********************************************************************/
-	//EnvDebugOn is the debug setting
-	cfm["EnvDebugOn"] = os.Getenv("DF_DEBUGON")
-	//EnvDfGcpProject is the project setting
-	cfm["EnvDfGcpProject"] = os.Getenv("DF_GCP_PROJECT")
-	//EnvDfParamsBucket is the GCS bucket storing the parameter files
-	cfm["EnvDfParamsBucket"] = os.Getenv("DF_BUCKET")
-	//EnvDfGcpRegion is the region setting
-	cfm["EnvDfGcpRegion"] = os.Getenv("DF_GCP_REGION")
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		fcm, err := LoadConfigFile(path)
+		if err != nil {
+			return cfm, err
+		}
 
-	if cfm["EnvDebugOn"] == "" {
-		log.Fatal("Could not parse environment variable EnvDebugOn")
+		cfm = fcm
 	}
 
-	if cfm["EnvDfGcpProject"] == "" {
-		log.Fatal("Could not parse environment variable EnvDfGcpProject")
+	for _, item := range configVars {
+		if val := os.Getenv(item.variable); val != "" {
+			cfm[item.setting] = val
+		}
 	}
 
-	if cfm["EnvDfGcpRegion"] == "" {
-		log.Fatal("Could not parse environment variable EnvDfGcpRegion")
+	return cfm, nil
+}
+
+//LoadConfigFile reads a json (.json) or yaml (.yaml, .yml) config file into a map of config settings
+//
+//The file is a flat object using the keys debugon, gcpproject, gcpregion, bucket, sqldst and sqlcnx.
+//The result can be applied to a ConfigSetting with LoadConfigMap.
+func LoadConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
 
-	if cfm["EnvDfParamsBucket"] == "" {
-		log.Fatal("Could not parse environment variablex EnvDfParamsBucket")
+	var raw map[string]interface{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("%s is not a .json, .yaml or .yml file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s could not be parsed: %v", path, err)
 	}
 
-	/**********************************************************************
-	* SQL ENV SETTINGS
-	**********************************************************************/
-	//EnvSqlDst is the client poolsize
-	cfm["EnvSqlDst"] = os.Getenv("DF_SQLDST")
-	//EnvSqlConnection is the datastore namespace
-	cfm["EnvSqlConnection"] = os.Getenv("DF_SQLCNX")
+	cfm := make(map[string]string)
 
-	if cfm["EnvSqlConnection"] == "" {
-		log.Fatal("Could not parse environment variable EnvSqlConnection")
+	for key, val := range raw {
+		cv, ok := configFileVar(key)
+		if !ok {
+			return nil, fmt.Errorf("%s contains an unknown setting %q", path, key)
+		}
+
+		switch val.(type) {
+		case string, bool, int, float64:
+			cfm[cv.setting] = fmt.Sprint(val)
+		default:
+			return nil, fmt.Errorf("%s setting %q must be a single value", path, key)
+		}
 	}
 
-	if cfm["EnvSqlDst"] == "" {
-		log.Fatal("Could not parse environment variablex EnvSqlDst")
+	return cfm, nil
+}
+
+//configFileVar finds the setting for a config file key
+func configFileVar(key string) (configVar, bool) {
+	for _, item := range configVars {
+		if item.key == key {
+			return item, true
+		}
 	}
 
-	return cfm
+	return configVar{}, false
 }
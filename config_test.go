@@ -0,0 +1,187 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cfg "github.com/lidstromberg/config"
+)
+
+//setTestEnv sets every DF_* variable, then clears or overrides those supplied
+func setTestEnv(t *testing.T, overrides map[string]string) {
+	env := map[string]string{
+		"DF_DEBUGON":     "false",
+		"DF_GCP_PROJECT": "testproject",
+		"DF_GCP_REGION":  "europe-west1",
+		"DF_BUCKET":      "testbucket",
+		"DF_SQLDST":      "postgres",
+		"DF_SQLCNX":      "host=127.0.0.1 port=5436",
+	}
+
+	for k, v := range overrides {
+		env[k] = v
+	}
+
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+}
+
+func Test_Preflight(t *testing.T) {
+	ctx := context.Background()
+	setTestEnv(t, nil)
+
+	bc := cfg.NewConfig(ctx)
+
+	debug, err := preflight(ctx, bc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if debug {
+		t.Fatal("expected debug to be off")
+	}
+
+	if bc.GetConfigValue(ctx, "EnvDfGcpProject") != "testproject" {
+		t.Fatalf("expected testproject, got %s", bc.GetConfigValue(ctx, "EnvDfGcpProject"))
+	}
+}
+func Test_PreflightErrors(t *testing.T) {
+	ctx := context.Background()
+	setTestEnv(t, map[string]string{
+		"DF_DEBUGON":     "maybe",
+		"DF_GCP_PROJECT": "",
+		"DF_SQLCNX":      "",
+	})
+
+	_, err := preflight(ctx, cfg.NewConfig(ctx), true)
+
+	var cerr *ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected a *ConfigError, got %v", err)
+	}
+
+	want := []string{"DF_GCP_PROJECT", "DF_SQLCNX", "DF_DEBUGON"}
+	if len(cerr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), cerr.Problems)
+	}
+
+	for i, item := range cerr.Problems {
+		if item.Variable != want[i] {
+			t.Fatalf("expected %s at %d, got %s", want[i], i, item.Variable)
+		}
+
+		if !strings.Contains(err.Error(), item.Variable) {
+			t.Fatalf("error %q does not mention %s", err.Error(), item.Variable)
+		}
+	}
+
+	//the managers report the error rather than exiting
+	if _, err = NewPgMgr(ctx, cfg.NewConfig(ctx)); !errors.As(err, &cerr) {
+		t.Fatalf("expected a *ConfigError, got %v", err)
+	}
+}
+func Test_PreflightConfigFile(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	yml := filepath.Join(dir, "dfmgr.yaml")
+	err := os.WriteFile(yml, []byte("debugon: true\ngcpproject: fileproject\ngcpregion: us-central1\nbucket: filebucket\nsqldst: sqlite\nsqlcnx: file.db\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the environment overrides the file
+	setTestEnv(t, map[string]string{
+		EnvConfigFile:    yml,
+		"DF_DEBUGON":     "",
+		"DF_GCP_PROJECT": "",
+		"DF_GCP_REGION":  "",
+		"DF_BUCKET":      "",
+		"DF_SQLDST":      "",
+		"DF_SQLCNX":      "",
+	})
+	t.Setenv("DF_GCP_REGION", "europe-west1")
+
+	bc := cfg.NewConfig(ctx)
+
+	_, err = preflight(ctx, bc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"EnvDebugOn":        "true",
+		"EnvDfGcpProject":   "fileproject",
+		"EnvDfGcpRegion":    "europe-west1",
+		"EnvDfParamsBucket": "filebucket",
+		"EnvSqlDst":         "sqlite",
+		"EnvSqlConnection":  "file.db",
+	}
+
+	for k, v := range want {
+		if bc.GetConfigValue(ctx, k) != v {
+			t.Fatalf("expected %s=%s, got %s", k, v, bc.GetConfigValue(ctx, k))
+		}
+	}
+}
+func Test_LoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	js := filepath.Join(dir, "dfmgr.json")
+	err := os.WriteFile(js, []byte(`{"debugon": false, "gcpproject": "jsonproject"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfm, err := LoadConfigFile(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfm["EnvDebugOn"] != "false" || cfm["EnvDfGcpProject"] != "jsonproject" || len(cfm) != 2 {
+		t.Fatalf("unexpected config %v", cfm)
+	}
+
+	//the example config file is valid
+	cfm, err = LoadConfigFile("env.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfm) != len(configVars) {
+		t.Fatalf("expected %d settings, got %v", len(configVars), cfm)
+	}
+
+	bad := map[string]string{
+		"unknown.json": `{"gcpzone": "europe-west1-b"}`,
+		"nested.yaml":  "gcpproject:\n  name: x\n",
+		"broken.json":  `{"gcpproject": `,
+		"dfmgr.toml":   `gcpproject = "x"`,
+	}
+
+	for name, content := range bad {
+		path := filepath.Join(dir, name)
+		if err = os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = LoadConfigFile(path); err == nil {
+			t.Fatalf("expected an error for %s", name)
+		}
+	}
+
+	//file problems are reported by preflight
+	setTestEnv(t, map[string]string{EnvConfigFile: filepath.Join(dir, "broken.json")})
+
+	_, err = preflight(context.Background(), cfg.NewConfig(context.Background()), true)
+
+	var cerr *ConfigError
+	if !errors.As(err, &cerr) || cerr.Problems[0].Variable != EnvConfigFile {
+		t.Fatalf("expected a %s problem, got %v", EnvConfigFile, err)
+	}
+}
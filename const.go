@@ -2,6 +2,12 @@ package dfmgr
 
 //taken from https://godoc.org/google.golang.org/api/dataflow/v1b3
 
+//JobState is the run state of a dataflow job (see jobstate.go for its classification)
+//
+//The CnstState* values are untyped constants, so that they still compare directly with the df.Job CurrentState and
+//RequestedState strings.
+type JobState string
+
 const (
 	//CnstStateUnknown The job's run state isn't specified.
 	CnstStateUnknown = "JOB_STATE_UNKNOWN"
@@ -27,4 +33,6 @@ const (
 	CnstStateCancelling = "JOB_STATE_CANCELLING"
 	//CnstStateQueued indicates that the job has been created but is being delayed until launch. Jobs that are queued may only transition to `JOB_STATE_PENDING` or `JOB_STATE_CANCELLED`.
 	CnstStateQueued = "JOB_STATE_QUEUED"
+	//CnstStateResourceCleaningUp indicates that the batch job's associated resources are currently being cleaned up after a successful run.  Currently, this is an opt-in feature, please reach out to Cloud support team if you are interested.
+	CnstStateResourceCleaningUp = "JOB_STATE_RESOURCE_CLEANING_UP"
 )
@@ -0,0 +1,95 @@
+package dfmgr
+
+import (
+	"context"
+
+	df "google.golang.org/api/dataflow/v1b3"
+)
+
+// DataflowClient defines the dataflow api calls used by DfMgr
+type DataflowClient interface {
+	LaunchTemplate(ctx context.Context, project, location string, req *df.CreateJobFromTemplateRequest) (*df.Job, error)
+	LaunchFlexTemplate(ctx context.Context, project, location string, req *df.LaunchFlexTemplateRequest) (*df.Job, error)
+	GetJob(ctx context.Context, project, location, jobID string) (*df.Job, error)
+	UpdateJob(ctx context.Context, project, location, jobID string, jb *df.Job) (*df.Job, error)
+	ListJobs(ctx context.Context, project, location string) ([]*df.Job, error)
+}
+
+//DataflowClient implementations
+var (
+	_ DataflowClient = (*dfServiceClient)(nil)
+	_ DataflowClient = (*FakeDataflowClient)(nil)
+)
+
+// dfServiceClient is the DataflowClient backed by the dataflow v1b3 api
+type dfServiceClient struct {
+	svc *df.Service
+}
+
+// NewDataflowClient returns a DataflowClient which calls the supplied dataflow service
+func NewDataflowClient(svc *df.Service) DataflowClient {
+	return &dfServiceClient{svc: svc}
+}
+
+// LaunchTemplate creates a job from a classic template
+func (dfc *dfServiceClient) LaunchTemplate(ctx context.Context, project, location string, req *df.CreateJobFromTemplateRequest) (*df.Job, error) {
+	svc := df.NewProjectsLocationsTemplatesService(dfc.svc)
+
+	jbr := svc.Create(project, location, req)
+	jbr.Context(ctx)
+
+	return jbr.Do()
+}
+
+// LaunchFlexTemplate creates a job from a flex template
+func (dfc *dfServiceClient) LaunchFlexTemplate(ctx context.Context, project, location string, req *df.LaunchFlexTemplateRequest) (*df.Job, error) {
+	svc := df.NewProjectsLocationsFlexTemplatesService(dfc.svc)
+
+	lcall := svc.Launch(project, location, req)
+	lcall.Context(ctx)
+
+	rs, err := lcall.Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return rs.Job, nil
+}
+
+// GetJob gets the current state of a job
+func (dfc *dfServiceClient) GetJob(ctx context.Context, project, location, jobID string) (*df.Job, error) {
+	jbsvc := df.NewProjectsLocationsJobsService(dfc.svc)
+
+	msgcall := jbsvc.Get(project, location, jobID)
+	msgcall.Context(ctx)
+
+	return msgcall.Do()
+}
+
+// UpdateJob updates a job (e.g. to request a state change)
+func (dfc *dfServiceClient) UpdateJob(ctx context.Context, project, location, jobID string, jb *df.Job) (*df.Job, error) {
+	jbsvc := df.NewProjectsLocationsJobsService(dfc.svc)
+
+	jbcl := jbsvc.Update(project, location, jobID, jb)
+	jbcl.Context(ctx)
+
+	return jbcl.Do()
+}
+
+// ListJobs lists all of the jobs in a project location
+func (dfc *dfServiceClient) ListJobs(ctx context.Context, project, location string) ([]*df.Job, error) {
+	jbsvc := df.NewProjectsLocationsJobsService(dfc.svc)
+
+	var jbs []*df.Job
+
+	lscall := jbsvc.List(project, location)
+	err := lscall.Pages(ctx, func(rs *df.ListJobsResponse) error {
+		jbs = append(jbs, rs.Jobs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jbs, nil
+}
@@ -0,0 +1,220 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lidstromberg/dataflowcontrol/dftest"
+
+	"google.golang.org/api/googleapi"
+)
+
+//newServerMgr returns a DfMgr which calls a local dataflow api server through the real client library
+func newServerMgr(ctx context.Context, t *testing.T) (*DfMgr, *dftest.Server, *MemMgr) {
+	srv := dftest.NewServer()
+	t.Cleanup(srv.Close)
+
+	svc, err := srv.Service(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mm := NewMemMgr(ctx)
+
+	dfm, err := NewMgrWithOptions(ctx,
+		WithDataflowService(svc),
+		WithJobStore(mm),
+		WithProject("testproject"),
+		WithRegion("europe-west1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dfm, srv, mm
+}
+
+func Test_ServerJobLifecycle(t *testing.T) {
+	ctx := context.Background()
+	dfm, srv, mm := newServerMgr(ctx, t)
+	srv.Script(CnstStatePending, CnstStateRunning, CnstStateRunning)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 || reqs[0].GcsPath != "gs://testproject/dataflow/templates/test" || reqs[0].Parameters["runner"] != "DataflowRunner" {
+		t.Fatalf("unexpected template request %v", reqs)
+	}
+
+	jb, err := dfm.GetJobStatus(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateRunning || jb.ProjectId != "testproject" {
+		t.Fatalf("unexpected job %v", jb)
+	}
+
+	jb, err = dfm.JobStop(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateCancelling {
+		t.Fatalf("expected %s, got %s", CnstStateCancelling, jb.CurrentState)
+	}
+
+	if _, err = dfm.GetJobStatus(ctx, meta.JobID); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := mm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.LastStatus != CnstStateCancelled {
+		t.Fatalf("expected %s, got %s", CnstStateCancelled, ds.LastStatus)
+	}
+}
+func Test_ServerErrors(t *testing.T) {
+	ctx := context.Background()
+	dfm, srv, _ := newServerMgr(ctx, t)
+
+	var gerr *googleapi.Error
+
+	//unknown jobs
+	_, err := dfm.GetJobStatus(ctx, "unknown")
+	if !errors.As(err, &gerr) || gerr.Code != http.StatusNotFound {
+		t.Fatalf("expected a 404 error, got %v", err)
+	}
+
+	//injected api errors
+	srv.FailNext(dftest.MethodTemplatesCreate, http.StatusForbidden)
+
+	_, err = dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if !errors.As(err, &gerr) || gerr.Code != http.StatusForbidden {
+		t.Fatalf("expected a 403 error, got %v", err)
+	}
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//malformed responses
+	srv.MalformNext(dftest.MethodJobsGet)
+
+	if _, err = dfm.GetJobStatus(ctx, meta.JobID); err == nil {
+		t.Fatal("expected a decode error")
+	}
+
+	//cancelling a terminal job is rejected before it reaches the api
+	srv.Script(CnstStateDone)
+
+	meta, err = dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dfm.JobStop(ctx, meta.JobID); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTransition, err)
+	}
+}
+func Test_ServerListJobs(t *testing.T) {
+	ctx := context.Background()
+	dfm, _, _ := newServerMgr(ctx, t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jbs, err := dfm.dfc.ListJobs(ctx, "testproject", "europe-west1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jbs) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(jbs))
+	}
+}
+func Test_ServerJobDrain(t *testing.T) {
+	ctx := context.Background()
+	dfm, srv, mm := newServerMgr(ctx, t)
+	srv.Script(CnstStateRunning)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb, err := dfm.JobDrain(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateDraining || jb.RequestedState != CnstStateDrained {
+		t.Fatalf("unexpected job %v", jb)
+	}
+
+	if jb, err = dfm.GetJobStatus(ctx, meta.JobID); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := mm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.LastStatus != CnstStateDrained || ds.StopMode != StopModeDrain {
+		t.Fatalf("unexpected job record %v", ds)
+	}
+}
+func Test_ServerFlexTemplate(t *testing.T) {
+	ctx := context.Background()
+	dfm, srv, mm := newServerMgr(ctx, t)
+	srv.Script(CnstStatePending, CnstStateRunning)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestFlexJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := srv.FlexRequests()
+	if len(reqs) != 1 || len(srv.Requests()) != 0 {
+		t.Fatalf("expected a single flex launch, got %v %v", reqs, srv.Requests())
+	}
+
+	lp := reqs[0].LaunchParameter
+	if lp.ContainerSpecGcsPath != "gs://testproject/dataflow/flextemplates/test.json" || lp.Environment.NumWorkers != 1 || lp.Parameters["outputTable"] != "testproject:testdataset.testtable" {
+		t.Fatalf("unexpected launch parameter %v", lp)
+	}
+
+	if _, err = dfm.GetJobStatus(ctx, meta.JobID); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := mm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.LastStatus != CnstStateRunning {
+		t.Fatalf("expected %s, got %s", CnstStateRunning, ds.LastStatus)
+	}
+
+	//api errors
+	srv.FailNext(dftest.MethodFlexTemplatesLaunch, http.StatusBadRequest)
+
+	var gerr *googleapi.Error
+	if _, err = dfm.JobStart(ctx, jbappscope, jobtype, newTestFlexJobParam()); !errors.As(err, &gerr) || gerr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a 400 error, got %v", err)
+	}
+}
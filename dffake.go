@@ -0,0 +1,266 @@
+package dfmgr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	df "google.golang.org/api/dataflow/v1b3"
+	"google.golang.org/api/googleapi"
+)
+
+// FakeDataflowClient is a scriptable, in-memory DataflowClient for tests
+//
+// Each launched job follows a script of states. The launch returns the first state and every
+// GetJob call advances the job one step, stopping at the final state. Requesting a cancel moves
+// the job to JOB_STATE_CANCELLING and then JOB_STATE_CANCELLED on the next GetJob, and requesting a drain moves a
+// running job to JOB_STATE_DRAINING and then JOB_STATE_DRAINED.
+type FakeDataflowClient struct {
+	mu       sync.Mutex
+	seq      int
+	script   []JobState
+	jobs     map[string]*fakeJob
+	order    []string
+	failures map[string][]error
+	launched []*df.CreateJobFromTemplateRequest
+	flex     []*df.LaunchFlexTemplateRequest
+}
+
+// fakeJob is a job and its remaining state script
+type fakeJob struct {
+	job    df.Job
+	script []JobState
+}
+
+// NewFakeDataflowClient returns a fake which runs jobs through QUEUED, PENDING, RUNNING and DONE
+func NewFakeDataflowClient() *FakeDataflowClient {
+	return &FakeDataflowClient{
+		script:   []JobState{CnstStateQueued, CnstStatePending, CnstStateRunning, CnstStateDone},
+		jobs:     make(map[string]*fakeJob),
+		failures: make(map[string][]error),
+	}
+}
+
+// Script sets the state sequence for subsequently launched jobs
+func (fc *FakeDataflowClient) Script(states ...JobState) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.script = append([]JobState(nil), states...)
+}
+
+// ScriptJob replaces the remaining state sequence of an existing job
+func (fc *FakeDataflowClient) ScriptJob(jobID string, states ...JobState) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fj, ok := fc.jobs[jobID]
+	if !ok {
+		return fakeNotFound(jobID)
+	}
+
+	fj.script = append([]JobState(nil), states...)
+
+	return nil
+}
+
+// AddJob registers a job which was not launched through the fake (e.g. one started from the console)
+func (fc *FakeDataflowClient) AddJob(jb *df.Job, states ...JobState) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.jobs[jb.Id] = &fakeJob{job: *jb, script: append([]JobState(nil), states...)}
+	fc.order = append(fc.order, jb.Id)
+}
+
+// FailNext makes the next call of the named method (LaunchTemplate, LaunchFlexTemplate, GetJob, UpdateJob or ListJobs) return err
+func (fc *FakeDataflowClient) FailNext(method string, err error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.failures[method] = append(fc.failures[method], err)
+}
+
+// Launched returns the template requests received by the fake
+func (fc *FakeDataflowClient) Launched() []*df.CreateJobFromTemplateRequest {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return append([]*df.CreateJobFromTemplateRequest(nil), fc.launched...)
+}
+
+// FlexLaunched returns the flex template requests received by the fake
+func (fc *FakeDataflowClient) FlexLaunched() []*df.LaunchFlexTemplateRequest {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return append([]*df.LaunchFlexTemplateRequest(nil), fc.flex...)
+}
+
+// LaunchTemplate creates a job which follows the current script
+func (fc *FakeDataflowClient) LaunchTemplate(ctx context.Context, project, location string, req *df.CreateJobFromTemplateRequest) (*df.Job, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.failure("LaunchTemplate"); err != nil {
+		return nil, err
+	}
+
+	fc.launched = append(fc.launched, req)
+
+	return fc.launch(project, location, req.JobName), nil
+}
+
+// LaunchFlexTemplate creates a job which follows the current script
+func (fc *FakeDataflowClient) LaunchFlexTemplate(ctx context.Context, project, location string, req *df.LaunchFlexTemplateRequest) (*df.Job, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.failure("LaunchFlexTemplate"); err != nil {
+		return nil, err
+	}
+
+	fc.flex = append(fc.flex, req)
+
+	var name string
+	if req.LaunchParameter != nil {
+		name = req.LaunchParameter.JobName
+	}
+
+	return fc.launch(project, location, name), nil
+}
+
+// launch creates a job which follows the current script, the caller must hold the lock
+func (fc *FakeDataflowClient) launch(project, location, name string) *df.Job {
+	fc.seq++
+
+	jb := df.Job{
+		Id:        fmt.Sprintf("fake-%06d", fc.seq),
+		Name:      name,
+		ProjectId: project,
+		Location:  location,
+	}
+
+	fj := &fakeJob{job: jb, script: append([]JobState(nil), fc.script...)}
+	fj.advance()
+
+	fc.jobs[jb.Id] = fj
+	fc.order = append(fc.order, jb.Id)
+
+	return fj.snapshot()
+}
+
+// GetJob advances a job one step through its script and returns it
+func (fc *FakeDataflowClient) GetJob(ctx context.Context, project, location, jobID string) (*df.Job, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.failure("GetJob"); err != nil {
+		return nil, err
+	}
+
+	fj, ok := fc.jobs[jobID]
+	if !ok {
+		return nil, fakeNotFound(jobID)
+	}
+
+	fj.advance()
+
+	return fj.snapshot(), nil
+}
+
+// UpdateJob applies a requested state change to a job
+func (fc *FakeDataflowClient) UpdateJob(ctx context.Context, project, location, jobID string, jb *df.Job) (*df.Job, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.failure("UpdateJob"); err != nil {
+		return nil, err
+	}
+
+	fj, ok := fc.jobs[jobID]
+	if !ok {
+		return nil, fakeNotFound(jobID)
+	}
+
+	if jb.RequestedState != "" && !canRequestState(JobState(fj.job.CurrentState), JobState(jb.RequestedState)) {
+		return nil, fakePrecondition(jobID, fj.job.CurrentState, jb.RequestedState)
+	}
+
+	switch JobState(jb.RequestedState) {
+	case CnstStateCancelled:
+		fj.job.RequestedState = jb.RequestedState
+		fj.job.CurrentState = string(CnstStateCancelling)
+		fj.script = []JobState{CnstStateCancelled}
+	case CnstStateDrained:
+		fj.job.RequestedState = jb.RequestedState
+		fj.job.CurrentState = string(CnstStateDraining)
+		fj.script = []JobState{CnstStateDrained}
+	}
+
+	return fj.snapshot(), nil
+}
+
+// ListJobs returns every job known to the fake, in the order they were created
+func (fc *FakeDataflowClient) ListJobs(ctx context.Context, project, location string) ([]*df.Job, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.failure("ListJobs"); err != nil {
+		return nil, err
+	}
+
+	jbs := make([]*df.Job, 0, len(fc.order))
+	for _, id := range fc.order {
+		if fj, ok := fc.jobs[id]; ok {
+			jbs = append(jbs, fj.snapshot())
+		}
+	}
+
+	return jbs, nil
+}
+
+// failure pops the next scripted failure for a method, the caller must hold the lock
+func (fc *FakeDataflowClient) failure(method string) error {
+	errs := fc.failures[method]
+	if len(errs) == 0 {
+		return nil
+	}
+
+	fc.failures[method] = errs[1:]
+
+	return errs[0]
+}
+
+// advance moves the job to the next scripted state
+func (fj *fakeJob) advance() {
+	if len(fj.script) == 0 {
+		return
+	}
+
+	fj.job.CurrentState = string(fj.script[0])
+	fj.script = fj.script[1:]
+}
+
+// snapshot returns a copy of the job
+func (fj *fakeJob) snapshot() *df.Job {
+	jb := fj.job
+	return &jb
+}
+
+// fakeNotFound returns the error dataflow gives for an unknown job
+func fakeNotFound(jobID string) error {
+	return &googleapi.Error{
+		Code:    http.StatusNotFound,
+		Message: fmt.Sprintf("job %s not found", jobID),
+	}
+}
+
+// fakePrecondition returns the error dataflow gives for a rejected state change
+func fakePrecondition(jobID, from, to string) error {
+	return &googleapi.Error{
+		Code:    http.StatusBadRequest,
+		Message: fmt.Sprintf("job %s cannot move from %s to %s", jobID, from, to),
+	}
+}
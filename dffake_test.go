@@ -0,0 +1,371 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	df "google.golang.org/api/dataflow/v1b3"
+	"google.golang.org/api/googleapi"
+)
+
+//newFakeMgr returns a DfMgr backed by a fake dataflow client and an in-memory job store
+func newFakeMgr(ctx context.Context, t *testing.T) (*DfMgr, *FakeDataflowClient, *MemMgr) {
+	fc := NewFakeDataflowClient()
+	mm := NewMemMgr(ctx)
+
+	dfm, err := NewMgrWithOptions(ctx,
+		WithDataflowClient(fc),
+		WithJobStore(mm),
+		WithProject("testproject"),
+		WithRegion("europe-west1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dfm, fc, mm
+}
+
+//newTestJobParam returns a job definition matching jobdef/dataflowjobdef.json
+func newTestJobParam() *JobRunParameter {
+	return &JobRunParameter{
+		CustomParameters: map[string]string{
+			"runner": "DataflowRunner",
+		},
+		RuntimeEnvironment: map[string]string{
+			"maxWorkers":   "1",
+			"machineType":  "n1-standard-1",
+			"numWorkers":   "1",
+			"tempLocation": "gs://testproject/dataflow/temp/",
+		},
+		JobRequest: map[string]string{
+			"jobName":  "dflauncher%s",
+			"jobType":  "df-etl",
+			"location": "europe-west1",
+			"gcsPath":  "gs://testproject/dataflow/templates/test",
+		},
+	}
+}
+
+//newTestFlexJobParam returns a job definition matching jobdef/flexjobdef.json
+func newTestFlexJobParam() *JobRunParameter {
+	return &JobRunParameter{
+		TemplateKind: TemplateKindFlex,
+		CustomParameters: map[string]string{
+			"outputTable": "testproject:testdataset.testtable",
+		},
+		RuntimeEnvironment: map[string]string{
+			"maxWorkers":   "2",
+			"machineType":  "n1-standard-1",
+			"numWorkers":   "1",
+			"tempLocation": "gs://testproject/dataflow/temp/",
+		},
+		JobRequest: map[string]string{
+			"jobName":              "dfflexlauncher%s",
+			"jobType":              "df-flex",
+			"location":             "europe-west1",
+			"containerSpecGcsPath": "gs://testproject/dataflow/flextemplates/test.json",
+		},
+	}
+}
+
+func Test_FakeJobScript(t *testing.T) {
+	ctx := context.Background()
+	fc := NewFakeDataflowClient()
+
+	jb, err := fc.LaunchTemplate(ctx, "testproject", "europe-west1", &df.CreateJobFromTemplateRequest{JobName: "testjob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{CnstStateQueued, CnstStatePending, CnstStateRunning, CnstStateDone, CnstStateDone}
+	got := []string{jb.CurrentState}
+
+	for i := 1; i < len(want); i++ {
+		jb, err = fc.GetJob(ctx, "testproject", "europe-west1", jb.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, jb.CurrentState)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+func Test_FakeCancel(t *testing.T) {
+	ctx := context.Background()
+	fc := NewFakeDataflowClient()
+	fc.Script(CnstStateRunning)
+
+	jb, err := fc.LaunchTemplate(ctx, "testproject", "europe-west1", &df.CreateJobFromTemplateRequest{JobName: "testjob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb.RequestedState = CnstStateCancelled
+
+	jb, err = fc.UpdateJob(ctx, "testproject", "europe-west1", jb.Id, jb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateCancelling {
+		t.Fatalf("expected %s, got %s", CnstStateCancelling, jb.CurrentState)
+	}
+
+	jb, err = fc.GetJob(ctx, "testproject", "europe-west1", jb.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateCancelled {
+		t.Fatalf("expected %s, got %s", CnstStateCancelled, jb.CurrentState)
+	}
+
+	//terminal jobs cannot be cancelled
+	_, err = fc.UpdateJob(ctx, "testproject", "europe-west1", jb.Id, jb)
+
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) || gerr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a 400 error, got %v", err)
+	}
+}
+func Test_FakeErrors(t *testing.T) {
+	ctx := context.Background()
+	fc := NewFakeDataflowClient()
+
+	_, err := fc.GetJob(ctx, "testproject", "europe-west1", "unknown")
+
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) || gerr.Code != http.StatusNotFound {
+		t.Fatalf("expected a 404 error, got %v", err)
+	}
+
+	errQuota := errors.New("quota exceeded")
+	fc.FailNext("LaunchTemplate", errQuota)
+
+	if _, err = fc.LaunchTemplate(ctx, "testproject", "europe-west1", &df.CreateJobFromTemplateRequest{JobName: "testjob"}); err != errQuota {
+		t.Fatalf("expected %v, got %v", errQuota, err)
+	}
+
+	//failures are only returned once
+	if _, err = fc.LaunchTemplate(ctx, "testproject", "europe-west1", &df.CreateJobFromTemplateRequest{JobName: "testjob"}); err != nil {
+		t.Fatal(err)
+	}
+}
+func Test_FakeMgrJobLifecycle(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, mm := newFakeMgr(ctx, t)
+	fc.Script(CnstStateQueued, CnstStateRunning, CnstStateRunning)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if meta.CurrentState != CnstStateQueued {
+		t.Fatalf("expected %s, got %s", CnstStateQueued, meta.CurrentState)
+	}
+
+	launched := fc.Launched()
+	if len(launched) != 1 || launched[0].Environment.MaxWorkers != 1 || launched[0].Location != "europe-west1" {
+		t.Fatalf("unexpected launch request %v", launched)
+	}
+
+	jb, err := dfm.GetJobStatus(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := mm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateRunning || ds.LastStatus != CnstStateRunning {
+		t.Fatalf("expected %s, got %s (stored %s)", CnstStateRunning, jb.CurrentState, ds.LastStatus)
+	}
+
+	jb, err = dfm.JobStop(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateCancelling {
+		t.Fatalf("expected %s, got %s", CnstStateCancelling, jb.CurrentState)
+	}
+
+	jb, err = dfm.GetJobStatus(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err = mm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateCancelled || ds.LastStatus != CnstStateCancelled {
+		t.Fatalf("expected %s, got %s (stored %s)", CnstStateCancelled, jb.CurrentState, ds.LastStatus)
+	}
+}
+func Test_FakeMgrJobDrain(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, mm := newFakeMgr(ctx, t)
+	fc.Script(CnstStateRunning)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dfm.JobStopMode(ctx, meta.JobID, "pause"); err != ErrInvalidStopMode {
+		t.Fatalf("expected %v, got %v", ErrInvalidStopMode, err)
+	}
+
+	jb, err := dfm.JobDrain(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateDraining {
+		t.Fatalf("expected %s, got %s", CnstStateDraining, jb.CurrentState)
+	}
+
+	ds, err := mm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.StopMode != StopModeDrain {
+		t.Fatalf("expected %s, got %s", StopModeDrain, ds.StopMode)
+	}
+
+	jb, err = dfm.WaitForJob(ctx, meta.JobID, &WaitOptions{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateDrained {
+		t.Fatalf("expected %s, got %s", CnstStateDrained, jb.CurrentState)
+	}
+
+	//drained jobs cannot be stopped again
+	var terr *TransitionError
+	if _, err = dfm.JobDrain(ctx, meta.JobID); !errors.As(err, &terr) || terr.From != CnstStateDrained {
+		t.Fatalf("expected a *TransitionError, got %v", err)
+	}
+
+	//only running jobs can be drained
+	fc.Script(CnstStatePending)
+
+	meta, err = dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fc.UpdateJob(ctx, "testproject", "europe-west1", meta.JobID, &df.Job{RequestedState: CnstStateDrained})
+
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) || gerr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a 400 error, got %v", err)
+	}
+}
+func Test_FakeMgrJobStopRequest(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, mm := newFakeMgr(ctx, t)
+	fc.Script(CnstStateRunning)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &StopRequest{Mode: StopModeCancel, Reason: "superseded by a new release", RequestedBy: "deploy-bot"}
+
+	jb, err := dfm.JobStopRequest(ctx, meta.JobID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the post-stop state is recorded with the request
+	ds, err := mm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.LastStatus != JobState(jb.CurrentState) || ds.LastStatus != CnstStateCancelling {
+		t.Fatalf("expected %s, got %s", CnstStateCancelling, ds.LastStatus)
+	}
+
+	if ds.StopMode != req.Mode || ds.StopReason != req.Reason || ds.StoppedBy != req.RequestedBy || ds.StoppedDate == nil {
+		t.Fatalf("unexpected job record %v", ds)
+	}
+
+	//a nil request cancels the job
+	fc.Script(CnstStatePending)
+
+	meta, err = dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dfm.JobStopRequest(ctx, meta.JobID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if ds, err = mm.GetJob(ctx, meta.JobID); err != nil || ds.StopMode != StopModeCancel {
+		t.Fatalf("expected %s, got %v (%v)", StopModeCancel, ds, err)
+	}
+}
+func Test_FakeMgrFlexTemplate(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, mm := newFakeMgr(ctx, t)
+	fc.Script(CnstStateQueued, CnstStateRunning)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestFlexJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fc.Launched()) != 0 {
+		t.Fatalf("expected no classic launches, got %v", fc.Launched())
+	}
+
+	flex := fc.FlexLaunched()
+	if len(flex) != 1 {
+		t.Fatalf("expected 1 flex launch, got %d", len(flex))
+	}
+
+	lp := flex[0].LaunchParameter
+	if lp.ContainerSpecGcsPath != "gs://testproject/dataflow/flextemplates/test.json" || lp.Environment.MaxWorkers != 2 || lp.Parameters["outputTable"] != "testproject:testdataset.testtable" {
+		t.Fatalf("unexpected launch parameter %v", lp)
+	}
+
+	ds, err := mm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.LastStatus != CnstStateQueued || ds.AppScope != jbappscope {
+		t.Fatalf("unexpected job %v", ds)
+	}
+
+	//unknown template kinds are rejected before launching
+	param := newTestFlexJobParam()
+	param.TemplateKind = "sql"
+
+	if _, err = dfm.JobStart(ctx, jbappscope, jobtype, param); err != ErrInvalidTemplateKind {
+		t.Fatalf("expected %v, got %v", ErrInvalidTemplateKind, err)
+	}
+
+	if len(fc.FlexLaunched()) != 1 {
+		t.Fatal("expected no further launches")
+	}
+}
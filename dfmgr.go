@@ -2,14 +2,13 @@ package dfmgr
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
 
 	cfg "github.com/lidstromberg/config"
-	lg "github.com/lidstromberg/log"
 	sto "github.com/lidstromberg/storage"
 
 	google "golang.org/x/oauth2/google"
@@ -18,10 +17,12 @@ import (
 
 // DfMgr covers job management functionality
 type DfMgr struct {
-	dfsvc *df.Service
-	ds    *PgMgr
-	st    *sto.StorMgr
-	bc    cfg.ConfigSetting
+	dfc     DataflowClient
+	ds      JobStore
+	jd      JobDefinitionSource
+	project string
+	region  string
+	log     *slog.Logger
 }
 
 // NewGoogleCredentials returns a GCP/Google credential from a supplied file.. reference... might not be needed
@@ -40,108 +41,178 @@ func NewGoogleCredentials(ctx context.Context, path string) (*google.Credentials
 	return creds, nil
 }
 
-// NewMgr returns a new manager
-func NewMgr(ctx context.Context, bc cfg.ConfigSetting) (*DfMgr, error) {
-	preflight(ctx, bc)
-
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "NewMgr", "info", "start")
-	}
-
-	//use this for deployment (it will use the service account within appengine)
-	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.full_control", "https://www.googleapis.com/auth/bigquery", "https://www.googleapis.com/auth/cloud-platform", "https://www.googleapis.com/auth/drive")
+// NewMgr returns a new manager which tracks jobs in the configured postgres db
+//
+// opts are applied after the configured settings, so e.g. WithLogger replaces the default logger (which is silent unless
+// EnvDebugOn is set) for both the manager and its postgres db store.
+func NewMgr(ctx context.Context, bc cfg.ConfigSetting, opts ...Option) (*DfMgr, error) {
+	debug, err := preflight(ctx, bc, true)
 	if err != nil {
 		return nil, err
 	}
 
-	//dataflow client
-	dfs, err := df.New(client)
+	logger := optionLogger(debug, opts)
+	logger.DebugContext(ctx, "start", "mgr", "DfMgr", "op", "NewMgr")
+
+	//data mgr
+	ds, err := newPgMgr(ctx, bc, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	//data mgr
-	ds, err := NewPgMgr(ctx, bc)
+	abm, err := newStoreMgr(ctx, bc, ds, logger, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	//storage client
-	stor, err := sto.NewMgr(ctx, bc)
+	logger.DebugContext(ctx, "end", "mgr", "DfMgr", "op", "NewMgr")
+
+	return abm, nil
+}
+
+// NewStoreMgr returns a new manager which tracks jobs in the supplied job store, opts are applied as for NewMgr
+//
+// The sql settings are not required, and the default dataflow client and job definition source are only built if opts do not
+// supply them, so e.g. a manager over NewMemMgr with WithDataflowClient and WithJobDefinitionSource needs no db or GCP credentials.
+func NewStoreMgr(ctx context.Context, bc cfg.ConfigSetting, ds JobStore, opts ...Option) (*DfMgr, error) {
+	debug, err := preflight(ctx, bc, false)
 	if err != nil {
 		return nil, err
 	}
 
-	//dataflow mgr
-	abm := &DfMgr{
-		dfsvc: dfs,
-		ds:    ds,
-		st:    stor,
-		bc:    bc,
+	return newStoreMgr(ctx, bc, ds, optionLogger(debug, opts), opts)
+}
+
+// newStoreMgr returns a new manager from settings which preflight has already loaded, the default dataflow client and job
+// definition source are only built if opts do not supply them
+func newStoreMgr(ctx context.Context, bc cfg.ConfigSetting, ds JobStore, logger *slog.Logger, opts []Option) (*DfMgr, error) {
+	o := newMgrOptions(opts)
+
+	base := []Option{
+		WithJobStore(ds),
+		WithProject(bc.GetConfigValue(ctx, "EnvDfGcpProject")),
+		WithRegion(bc.GetConfigValue(ctx, "EnvDfGcpRegion")),
+		WithLogger(logger),
+	}
+
+	if o.dfc == nil && o.dfsvc == nil && o.httpClient == nil {
+		//use this for deployment (it will use the service account within appengine)
+		client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.full_control", "https://www.googleapis.com/auth/bigquery", "https://www.googleapis.com/auth/cloud-platform", "https://www.googleapis.com/auth/drive")
+		if err != nil {
+			return nil, err
+		}
+
+		base = append(base, WithHTTPClient(client))
+	}
+
+	if o.jd == nil {
+		//storage client
+		stor, err := sto.NewMgr(ctx, bc)
+		if err != nil {
+			return nil, err
+		}
+
+		base = append(base, WithJobDefinitionSource(NewGcsJobDefinitionSource(stor, bc.GetConfigValue(ctx, "EnvDfParamsBucket"))))
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "NewMgr", "info", "end")
+	//dataflow mgr
+	abm, err := NewMgrWithOptions(ctx, append(base, opts...)...)
+	if err != nil {
+		return nil, err
 	}
 
 	return abm, nil
 }
 
-// JobStart starts a job from a template
-func (dfm *DfMgr) JobStart(ctx context.Context, appscope, jobtype string, jobParam *JobRunParameter) (*JobSimpleMeta, error) {
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "JobStart", "info", "start")
+// JobStart starts a job from a classic or flex template (see JobRunParameter.TemplateKind)
+//
+// If dataflow reports a state which is not one of the CnstState* values, the job is still recorded (as CnstStateUnknown) and its
+// meta is returned along with an ErrUnknownJobState error, so the caller should not launch it again.
+func (dfm *DfMgr) JobStart(ctx context.Context, appscope, jobtype string, jobParam *JobRunParameter) (_ *JobSimpleMeta, err error) {
+	if jobParam == nil {
+		return nil, ErrNoJobDefinition
 	}
 
-	//runtime parameters
-	param := make(map[string]string)
-	param = jobParam.CustomParameters
+	op := beginOp(ctx, dfm.log, "JobStart", "appscope", appscope, "jobtype", jobtype, "templatekind", jobParam.TemplateKind)
+	defer op.end(&err)
 
-	//convert the int64 strings
-	mxwrk, err := strconv.ParseInt(jobParam.RuntimeEnvironment["maxWorkers"], 10, 64)
+	//convert the legacy definition
+	jd, err := jobParam.Definition()
 	if err != nil {
 		return nil, err
 	}
 
-	nmwrk, err := strconv.ParseInt(jobParam.RuntimeEnvironment["numWorkers"], 10, 64)
-	if err != nil {
-		return nil, err
+	return dfm.startJob(ctx, op, appscope, jobtype, jd)
+}
+
+// JobStartDefinition starts a job from a typed job definition (see ParseJobDefinition)
+func (dfm *DfMgr) JobStartDefinition(ctx context.Context, appscope, jobtype string, jd *JobDefinition) (_ *JobSimpleMeta, err error) {
+	if jd == nil {
+		return nil, ErrNoJobDefinition
 	}
 
-	//runtime
-	rn := &df.RuntimeEnvironment{}
-	rn.MaxWorkers = mxwrk
-	rn.MachineType = jobParam.RuntimeEnvironment["machineType"]
-	rn.NumWorkers = nmwrk
-	rn.TempLocation = jobParam.RuntimeEnvironment["tempLocation"]
+	op := beginOp(ctx, dfm.log, "JobStartDefinition", "appscope", appscope, "jobtype", jobtype, "templatekind", jd.TemplateKind)
+	defer op.end(&err)
+
+	return dfm.startJob(ctx, op, appscope, jobtype, jd)
+}
+
+// startJob validates and launches a job definition, then records the job
+func (dfm *DfMgr) startJob(ctx context.Context, op *opLog, appscope, jobtype string, jd *JobDefinition) (*JobSimpleMeta, error) {
+	if err := jd.Validate(dfm.region); err != nil {
+		return nil, err
+	}
 
 	//current timestamp
 	now := time.Now()
 	dt := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), 0, time.UTC).Unix()
 
-	jobname := fmt.Sprintf(jobParam.JobRequest["jobName"], strconv.FormatInt(dt, 10))
-
-	//job request
-	jbc := &df.CreateJobFromTemplateRequest{}
-	jbc.JobName = jobname
-	jbc.Location = jobParam.JobRequest["location"]
-	jbc.GcsPath = jobParam.JobRequest["gcsPath"]
-	jbc.Environment = rn
-	jbc.Parameters = param
+	jobname := fmt.Sprintf(jd.JobName, strconv.FormatInt(dt, 10))
 
-	//get a new templates service
-	svc := df.NewProjectsLocationsTemplatesService(dfm.dfsvc)
-
-	//create the caller and set the context
-	jbr := svc.Create(dfm.bc.GetConfigValue(ctx, "EnvDfGcpProject"), dfm.bc.GetConfigValue(ctx, "EnvDfGcpRegion"), jbc)
-	jbr.Context(ctx)
-
-	//then run the job
-	jb, err := jbr.Do()
+	//runtime
+	rn := jd.Environment.environment()
+
+	//run the job
+	var (
+		jb  *df.Job
+		err error
+	)
+
+	switch jd.TemplateKind {
+	case TemplateKindFlex:
+		//flex job request
+		lp := &df.LaunchFlexTemplateParameter{}
+		lp.JobName = jobname
+		lp.ContainerSpecGcsPath = jd.TemplatePath
+		lp.Parameters = jd.Parameters
+
+		lp.Environment, err = newFlexRuntimeEnvironment(rn)
+		if err != nil {
+			return nil, err
+		}
+
+		jb, err = dfm.dfc.LaunchFlexTemplate(ctx, dfm.project, dfm.region, &df.LaunchFlexTemplateRequest{LaunchParameter: lp})
+	default:
+		//job request
+		jbc := &df.CreateJobFromTemplateRequest{}
+		jbc.JobName = jobname
+		jbc.Location = jd.Location
+		jbc.GcsPath = jd.TemplatePath
+		jbc.Environment = rn
+		jbc.Parameters = jd.Parameters
+
+		jb, err = dfm.dfc.LaunchTemplate(ctx, dfm.project, dfm.region, jbc)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	op.add("jobid", jb.Id, "jobname", jobname)
+	op.log.InfoContext(ctx, "launched", "jobstate", jb.CurrentState)
+
+	//an unrecognised state is recorded as CnstStateUnknown, as the job is running and must still be tracked
+	state, perr := ParseJobState(jb.CurrentState)
+
 	//archive info
 	dsjb := &DsJob{}
 
@@ -151,13 +222,13 @@ func (dfm *DfMgr) JobStart(ctx context.Context, appscope, jobtype string, jobPar
 	dsjb.JobType = jobtype
 	dsjb.CreatedDate = &now
 	dsjb.LastTouched = &now
-	dsjb.LastStatus = jb.CurrentState
+	dsjb.LastStatus = state
 
 	//collect the basic meta required to track the job
 	jbmeta := &JobSimpleMeta{
 		JobID:        jb.Id,
-		JobType:      jobParam.JobRequest["jobType"],
-		CurrentState: jb.CurrentState,
+		JobType:      jd.JobType,
+		CurrentState: state,
 	}
 
 	//save the job.. if the datastore save fails, don't fail the entire action.. just report the save failure
@@ -166,8 +237,9 @@ func (dfm *DfMgr) JobStart(ctx context.Context, appscope, jobtype string, jobPar
 		return nil, err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "JobStart", "info", "end")
+	//the job has been recorded, so return its meta along with the state error
+	if perr != nil {
+		return jbmeta, perr
 	}
 
 	//probably only need to track the jobid,
@@ -175,38 +247,69 @@ func (dfm *DfMgr) JobStart(ctx context.Context, appscope, jobtype string, jobPar
 }
 
 // GetJobStatus gets a job from an id
-func (dfm *DfMgr) GetJobStatus(ctx context.Context, jobID string) (*df.Job, error) {
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "JobStatus", "info", "start")
-	}
-
-	jbsvc := df.NewProjectsLocationsJobsService(dfm.dfsvc)
-
-	msgcall := jbsvc.Get(dfm.bc.GetConfigValue(ctx, "EnvDfGcpProject"), dfm.bc.GetConfigValue(ctx, "EnvDfGcpRegion"), jobID)
-	msgcall.Context(ctx)
+func (dfm *DfMgr) GetJobStatus(ctx context.Context, jobID string) (_ *df.Job, err error) {
+	op := beginOp(ctx, dfm.log, "GetJobStatus", "jobid", jobID)
+	defer op.end(&err)
 
-	jb, err := msgcall.Do()
+	jb, err := dfm.dfc.GetJob(ctx, dfm.project, dfm.region, jobID)
 	if err != nil {
 		return nil, err
 	}
 
-	//update the job status record
-	err = dfm.ds.SetJobStatus(ctx, jobID, jb.CurrentState)
+	state, err := ParseJobState(jb.CurrentState)
 	if err != nil {
 		return nil, err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "JobStatus", "info", "end")
+	//update the job status record
+	err = dfm.ds.SetJobStatus(ctx, jobID, state)
+	if err != nil {
+		return nil, err
 	}
 
 	return jb, nil
 }
 
-// JobStop stops a job
+// JobStop stops a job by cancelling it (this includes jobs which are queued, pending or draining)
 func (dfm *DfMgr) JobStop(ctx context.Context, jobID string) (*df.Job, error) {
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "JobStop", "info", "start")
+	return dfm.JobStopMode(ctx, jobID, StopModeCancel)
+}
+
+// JobDrain stops a job by draining it, so that data which is in-flight is processed (the job moves through JOB_STATE_DRAINING to JOB_STATE_DRAINED)
+func (dfm *DfMgr) JobDrain(ctx context.Context, jobID string) (*df.Job, error) {
+	return dfm.JobStopMode(ctx, jobID, StopModeDrain)
+}
+
+// JobStopMode stops a job by cancelling or draining it
+func (dfm *DfMgr) JobStopMode(ctx context.Context, jobID string, mode StopMode) (*df.Job, error) {
+	return dfm.JobStopRequest(ctx, jobID, &StopRequest{Mode: mode})
+}
+
+// JobStopRequest stops a job by cancelling or draining it, then records the request (mode, reason and requester) and the
+// job's resulting state (e.g. JOB_STATE_CANCELLING) in the job store
+//
+// A job can be cancelled from any state until it has finished (or is already cancelling), but only drained while it is running.
+// If the job's current state does not allow the stop, a *TransitionError is returned.
+func (dfm *DfMgr) JobStopRequest(ctx context.Context, jobID string, req *StopRequest) (_ *df.Job, err error) {
+	if req == nil {
+		req = &StopRequest{Mode: StopModeCancel}
+	}
+
+	op := beginOp(ctx, dfm.log, "JobStop", "jobid", jobID, "stopmode", req.Mode, "stopreason", req.Reason, "stoppedby", req.RequestedBy)
+	defer op.end(&err)
+
+	var (
+		requested JobState
+		allowed   func(JobState) bool
+	)
+
+	switch req.Mode {
+	case StopModeCancel:
+		requested, allowed = CnstStateCancelled, JobState.CanCancel
+	case StopModeDrain:
+		requested, allowed = CnstStateDrained, JobState.CanDrain
+	default:
+		return nil, ErrInvalidStopMode
 	}
 
 	//first get the job status
@@ -215,140 +318,153 @@ func (dfm *DfMgr) JobStop(ctx context.Context, jobID string) (*df.Job, error) {
 		return nil, err
 	}
 
-	//if the job state isn't running, then it can't be cancelled, so return the job as it is
-	if currJb.CurrentState != CnstStateRunning {
-		return currJb, nil
+	//check the stop is legal from the job's current state (which GetJobStatus has validated)
+	from := JobState(currJb.CurrentState)
+	if !allowed(from) {
+		return nil, &TransitionError{JobID: jobID, From: from, Requested: requested}
 	}
 
-	jbsvc := df.NewProjectsLocationsJobsService(dfm.dfsvc)
-
-	msgcall := jbsvc.Get(dfm.bc.GetConfigValue(ctx, "EnvDfGcpProject"), dfm.bc.GetConfigValue(ctx, "EnvDfGcpRegion"), jobID)
-	msgcall.Context(ctx)
+	//request the stop against the job we've just read
+	currJb.RequestedState = requested.String()
 
-	jb, err := msgcall.Do()
+	jb, err := dfm.dfc.UpdateJob(ctx, dfm.project, dfm.region, jobID, currJb)
 	if err != nil {
 		return nil, err
 	}
 
-	jb.RequestedState = CnstStateCancelled
+	op.log.InfoContext(ctx, "stop requested", "jobstate", jb.CurrentState)
 
-	jbcl := jbsvc.Update(dfm.bc.GetConfigValue(ctx, "EnvDfGcpProject"), dfm.bc.GetConfigValue(ctx, "EnvDfGcpRegion"), jobID, jb)
-	jbcl.Context(ctx)
-
-	jb, err = jbcl.Do()
+	state, err := ParseJobState(jb.CurrentState)
 	if err != nil {
 		return nil, err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "JobStop", "info", "end")
+	//record the request against the job, along with the state it left the job in
+	err = dfm.ds.SetJobStop(ctx, jobID, state, req)
+	if err != nil {
+		return nil, err
 	}
 
 	return jb, nil
 }
 
-// GetGcsJobDefinition retrieves a GCS bucket hosted set of parameters for a dataflow job
-func (dfm *DfMgr) GetGcsJobDefinition(ctx context.Context, filename string) (*JobRunParameter, error) {
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "GetGcsJobDefinition", "info", "start")
-	}
+// GetGcsJobDefinition retrieves a GCS bucket hosted set of parameters for a dataflow job (or from the configured job definition source)
+func (dfm *DfMgr) GetGcsJobDefinition(ctx context.Context, filename string) (_ *JobRunParameter, err error) {
+	op := beginOp(ctx, dfm.log, "GetGcsJobDefinition", "jobdefinition", filename)
+	defer op.end(&err)
 
-	var param *JobRunParameter
+	if dfm.jd == nil {
+		return nil, ErrNoJobDefinitionSource
+	}
 
-	//get the bucket bytes
-	data, err := dfm.st.GetBucketFileData(ctx, dfm.bc.GetConfigValue(ctx, "EnvDfParamsBucket"), filename)
+	param, err := dfm.jd.GetJobDefinition(ctx, filename)
 	if err != nil {
 		return nil, err
 	}
 
-	//unmarshall into the parameter object
-	err = json.Unmarshal(data, &param)
-	if err != nil {
-		return nil, err
+	return param, nil
+}
+
+// GetJobDefinition retrieves a job definition from the configured job definition source, in the typed format
+func (dfm *DfMgr) GetJobDefinition(ctx context.Context, filename string) (_ *JobDefinition, err error) {
+	op := beginOp(ctx, dfm.log, "GetJobDefinition", "jobdefinition", filename)
+	defer op.end(&err)
+
+	if dfm.jd == nil {
+		return nil, ErrNoJobDefinitionSource
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "GetGcsJobDefinition", "info", "end")
+	param, err := dfm.jd.GetJobDefinition(ctx, filename)
+	if err != nil {
+		return nil, err
 	}
 
-	return param, nil
+	return param.Definition()
 }
 
-// SetGcsJobDefinition retrieves a GCS bucket hosted set of parameters for a dataflow job
+// SetGcsJobDefinition writes a GCS bucket hosted set of parameters for a dataflow job (or to the configured job definition source)
+//
+// Deprecated: contenttype is ignored, as the object content type is determined by the job definition source. Use SetJobDefinition.
 func (dfm *DfMgr) SetGcsJobDefinition(ctx context.Context, filename, contenttype string, jd *JobRunParameter) error {
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "SetGcsJobDefinition", "info", "start")
-	}
+	return dfm.SetJobDefinition(ctx, filename, jd)
+}
 
-	//convert the job definition to json bytes
-	data, err := json.Marshal(jd)
-	if err != nil {
-		return err
+// SetJobDefinition writes a set of parameters for a dataflow job to the configured job definition source
+func (dfm *DfMgr) SetJobDefinition(ctx context.Context, filename string, jd *JobRunParameter) (err error) {
+	op := beginOp(ctx, dfm.log, "SetJobDefinition", "jobdefinition", filename)
+	defer op.end(&err)
+
+	if dfm.jd == nil {
+		return ErrNoJobDefinitionSource
 	}
 
-	//get the bucket bytes
-	err = dfm.st.WriteBucketFile(ctx, dfm.bc.GetConfigValue(ctx, "EnvDfParamsBucket"), contenttype, filename, data)
+	err = dfm.jd.SetJobDefinition(ctx, filename, jd)
 	if err != nil {
 		return err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "SetGcsJobDefinition", "info", "end")
-	}
-
 	return nil
 }
 
 // GetJob gets a job by id
-func (dfm *DfMgr) GetJob(ctx context.Context, jobID string) (*DsJob, error) {
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "GetJob", "info", "start")
-	}
+func (dfm *DfMgr) GetJob(ctx context.Context, jobID string) (_ *DsJob, err error) {
+	op := beginOp(ctx, dfm.log, "GetJob", "jobid", jobID)
+	defer op.end(&err)
 
 	jb, err := dfm.ds.GetJob(ctx, jobID)
 	if err != nil {
 		return nil, err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "GetJob", "info", "end")
-	}
-
 	return jb, nil
 }
 
 // GetJobs gets a list of jobs for an appscope
-func (dfm *DfMgr) GetJobs(ctx context.Context, appscope, jobtype, jobstate string) ([]*DsJob, error) {
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "GetActiveJobs", "info", "start")
-	}
+func (dfm *DfMgr) GetJobs(ctx context.Context, appscope, jobtype string, jobstate JobState) (_ []*DsJob, err error) {
+	op := beginOp(ctx, dfm.log, "GetJobs", "appscope", appscope, "jobtype", jobtype, "jobstate", jobstate)
+	defer op.end(&err)
 
 	jbs, err := dfm.ds.GetAppScopeJobs(ctx, appscope, jobtype, jobstate)
 	if err != nil {
 		return nil, err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "GetActiveJobs", "info", "end")
+	return jbs, nil
+}
+
+// GetActiveJobs gets the jobs for an appscope (and jobtype, which can be empty string) which have not reached a terminal state
+func (dfm *DfMgr) GetActiveJobs(ctx context.Context, appscope, jobtype string) (_ []*DsJob, err error) {
+	op := beginOp(ctx, dfm.log, "GetActiveJobs", "appscope", appscope, "jobtype", jobtype)
+	defer op.end(&err)
+
+	jbs, err := dfm.ds.GetAppScopeJobs(ctx, appscope, jobtype, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var active []*DsJob
+	for _, jb := range jbs {
+		if jb.LastStatus.IsActive() {
+			active = append(active, jb)
+		}
 	}
 
-	return jbs, nil
+	if len(active) == 0 {
+		return nil, ErrNoDataFound
+	}
+
+	return active, nil
 }
 
 // GetLatestJobs gets the most recent job for an appscope
-func (dfm *DfMgr) GetLatestJobs(ctx context.Context, appscope, jobtype string, limit int) ([]*DsJob, error) {
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "GetLatestJob", "info", "start")
-	}
+func (dfm *DfMgr) GetLatestJobs(ctx context.Context, appscope, jobtype string, limit int) (_ []*DsJob, err error) {
+	op := beginOp(ctx, dfm.log, "GetLatestJobs", "appscope", appscope, "jobtype", jobtype, "limit", limit)
+	defer op.end(&err)
 
 	jbs, err := dfm.ds.GetLatestAppScopeJob(ctx, appscope, jobtype, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("DfMgr", "GetLatestJob", "info", "end")
-	}
-
 	return jbs, nil
 }
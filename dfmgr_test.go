@@ -227,7 +227,7 @@ func Test_PollJobs(t *testing.T) {
 		jbmeta := &JobSimpleMeta{
 			JobID:        jb.Id,
 			JobType:      jobtype,
-			CurrentState: jb.CurrentState,
+			CurrentState: JobState(jb.CurrentState),
 		}
 
 		jbs = append(jbs, jbmeta)
@@ -0,0 +1,364 @@
+// Package dftest provides a local HTTP stand-in for the subset of the Dataflow v1b3 REST api used by dfmgr.
+//
+// Point a genuine dataflow client at the server with Service (or option.WithEndpoint(srv.URL+"/")) to run
+// JobStart, GetJobStatus and JobStop end to end without GCP.
+package dftest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	df "google.golang.org/api/dataflow/v1b3"
+	"google.golang.org/api/option"
+)
+
+//api method names, used to target injected failures
+const (
+	//MethodTemplatesCreate is projects.locations.templates.create
+	MethodTemplatesCreate = "templates.create"
+	//MethodFlexTemplatesLaunch is projects.locations.flexTemplates.launch
+	MethodFlexTemplatesLaunch = "flexTemplates.launch"
+	//MethodJobsGet is projects.locations.jobs.get
+	MethodJobsGet = "jobs.get"
+	//MethodJobsUpdate is projects.locations.jobs.update
+	MethodJobsUpdate = "jobs.update"
+	//MethodJobsList is projects.locations.jobs.list
+	MethodJobsList = "jobs.list"
+)
+
+//job states used by the server (mirrors the dfmgr CnstState* values)
+const (
+	stateQueued     = "JOB_STATE_QUEUED"
+	statePending    = "JOB_STATE_PENDING"
+	stateRunning    = "JOB_STATE_RUNNING"
+	stateDone       = "JOB_STATE_DONE"
+	stateFailed     = "JOB_STATE_FAILED"
+	stateCancelled  = "JOB_STATE_CANCELLED"
+	stateCancelling = "JOB_STATE_CANCELLING"
+	stateUpdated    = "JOB_STATE_UPDATED"
+	stateDraining   = "JOB_STATE_DRAINING"
+	stateDrained    = "JOB_STATE_DRAINED"
+)
+
+//Server is a scriptable dataflow api server
+//
+//Jobs follow a script of states in the same way as dfmgr.FakeDataflowClient: the create call returns the
+//first state and every jobs.get call advances the job one step.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	seq      int
+	script   []string
+	jobs     map[string]*job
+	order    []string
+	failures map[string][]failure
+	requests []*df.CreateJobFromTemplateRequest
+	flex     []*df.LaunchFlexTemplateRequest
+}
+
+//job is a job and its remaining state script
+type job struct {
+	job    df.Job
+	script []string
+}
+
+//failure is an injected response, either an api error or a malformed body
+type failure struct {
+	status    int
+	malformed bool
+}
+
+//NewServer starts a server which runs jobs through QUEUED, PENDING, RUNNING and DONE, the caller must Close it
+func NewServer() *Server {
+	srv := &Server{
+		script:   []string{stateQueued, statePending, stateRunning, stateDone},
+		jobs:     make(map[string]*job),
+		failures: make(map[string][]failure),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1b3/projects/{projectId}/locations/{location}/templates", srv.templatesCreate)
+	mux.HandleFunc("POST /v1b3/projects/{projectId}/locations/{location}/flexTemplates:launch", srv.flexTemplatesLaunch)
+	mux.HandleFunc("GET /v1b3/projects/{projectId}/locations/{location}/jobs", srv.jobsList)
+	mux.HandleFunc("GET /v1b3/projects/{projectId}/locations/{location}/jobs/{jobId}", srv.jobsGet)
+	mux.HandleFunc("PUT /v1b3/projects/{projectId}/locations/{location}/jobs/{jobId}", srv.jobsUpdate)
+
+	srv.Server = httptest.NewServer(mux)
+
+	return srv
+}
+
+//Service returns a genuine dataflow client which calls this server
+func (srv *Server) Service(ctx context.Context) (*df.Service, error) {
+	return df.NewService(ctx, option.WithEndpoint(srv.URL+"/"), option.WithHTTPClient(srv.Client()))
+}
+
+//Script sets the state sequence for subsequently created jobs
+func (srv *Server) Script(states ...string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.script = append([]string(nil), states...)
+}
+
+//AddJob registers a job which was not created through the server
+func (srv *Server) AddJob(jb *df.Job, states ...string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.jobs[jb.Id] = &job{job: *jb, script: append([]string(nil), states...)}
+	srv.order = append(srv.order, jb.Id)
+}
+
+//FailNext makes the next call of an api method return an error with the supplied http status
+func (srv *Server) FailNext(method string, status int) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.failures[method] = append(srv.failures[method], failure{status: status})
+}
+
+//MalformNext makes the next call of an api method return a 200 with a body which is not valid json
+func (srv *Server) MalformNext(method string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.failures[method] = append(srv.failures[method], failure{status: http.StatusOK, malformed: true})
+}
+
+//Requests returns the template create requests received by the server
+func (srv *Server) Requests() []*df.CreateJobFromTemplateRequest {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return append([]*df.CreateJobFromTemplateRequest(nil), srv.requests...)
+}
+
+//FlexRequests returns the flex template launch requests received by the server
+func (srv *Server) FlexRequests() []*df.LaunchFlexTemplateRequest {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return append([]*df.LaunchFlexTemplateRequest(nil), srv.flex...)
+}
+
+//templatesCreate handles projects.locations.templates.create
+func (srv *Server) templatesCreate(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.fail(w, MethodTemplatesCreate) {
+		return
+	}
+
+	var req df.CreateJobFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	srv.requests = append(srv.requests, &req)
+
+	jb := srv.create(r, req.JobName)
+
+	writeJSON(w, &jb.job)
+}
+
+//flexTemplatesLaunch handles projects.locations.flexTemplates.launch
+func (srv *Server) flexTemplatesLaunch(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.fail(w, MethodFlexTemplatesLaunch) {
+		return
+	}
+
+	var req df.LaunchFlexTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.LaunchParameter == nil || req.LaunchParameter.ContainerSpecGcsPath == "" {
+		writeError(w, http.StatusBadRequest, "launchParameter.containerSpecGcsPath is required")
+		return
+	}
+
+	srv.flex = append(srv.flex, &req)
+
+	jb := srv.create(r, req.LaunchParameter.JobName)
+
+	writeJSON(w, &df.LaunchFlexTemplateResponse{Job: &jb.job})
+}
+
+//create registers a job which follows the current script, the caller must hold the lock
+func (srv *Server) create(r *http.Request, name string) *job {
+	srv.seq++
+
+	jb := &job{
+		job: df.Job{
+			Id:        fmt.Sprintf("dftest-%06d", srv.seq),
+			Name:      name,
+			ProjectId: r.PathValue("projectId"),
+			Location:  r.PathValue("location"),
+		},
+		script: append([]string(nil), srv.script...),
+	}
+	jb.advance()
+
+	srv.jobs[jb.job.Id] = jb
+	srv.order = append(srv.order, jb.job.Id)
+
+	return jb
+}
+
+//jobsGet handles projects.locations.jobs.get
+func (srv *Server) jobsGet(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.fail(w, MethodJobsGet) {
+		return
+	}
+
+	jb, ok := srv.jobs[r.PathValue("jobId")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("job %s not found", r.PathValue("jobId")))
+		return
+	}
+
+	jb.advance()
+
+	writeJSON(w, &jb.job)
+}
+
+//jobsUpdate handles projects.locations.jobs.update (only cancel and drain requests are supported)
+func (srv *Server) jobsUpdate(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.fail(w, MethodJobsUpdate) {
+		return
+	}
+
+	jb, ok := srv.jobs[r.PathValue("jobId")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("job %s not found", r.PathValue("jobId")))
+		return
+	}
+
+	var req df.Job
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch req.RequestedState {
+	case stateCancelled:
+		if terminal(jb.job.CurrentState) || jb.job.CurrentState == stateCancelling {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("job %s cannot move from %s to %s", jb.job.Id, jb.job.CurrentState, req.RequestedState))
+			return
+		}
+		jb.job.RequestedState = req.RequestedState
+		jb.job.CurrentState = stateCancelling
+		jb.script = []string{stateCancelled}
+	case stateDrained:
+		if jb.job.CurrentState != stateRunning {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("job %s cannot move from %s to %s", jb.job.Id, jb.job.CurrentState, req.RequestedState))
+			return
+		}
+		jb.job.RequestedState = req.RequestedState
+		jb.job.CurrentState = stateDraining
+		jb.script = []string{stateDrained}
+	case "":
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("job %s cannot move from %s to %s", jb.job.Id, jb.job.CurrentState, req.RequestedState))
+		return
+	}
+
+	writeJSON(w, &jb.job)
+}
+
+//jobsList handles projects.locations.jobs.list, returning every job in a single page
+func (srv *Server) jobsList(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.fail(w, MethodJobsList) {
+		return
+	}
+
+	rs := &df.ListJobsResponse{}
+	for _, id := range srv.order {
+		if jb, ok := srv.jobs[id]; ok {
+			item := jb.job
+			rs.Jobs = append(rs.Jobs, &item)
+		}
+	}
+
+	writeJSON(w, rs)
+}
+
+//fail writes the next injected failure for a method, reporting whether it did so; the caller must hold the lock
+func (srv *Server) fail(w http.ResponseWriter, method string) bool {
+	fls := srv.failures[method]
+	if len(fls) == 0 {
+		return false
+	}
+
+	srv.failures[method] = fls[1:]
+
+	if fls[0].malformed {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "malformed`)
+		return true
+	}
+
+	writeError(w, fls[0].status, fmt.Sprintf("injected %s failure", method))
+
+	return true
+}
+
+//advance moves the job to the next scripted state
+func (jb *job) advance() {
+	if len(jb.script) == 0 {
+		return
+	}
+
+	jb.job.CurrentState = jb.script[0]
+	jb.script = jb.script[1:]
+}
+
+//terminal reports whether a state is terminal
+func terminal(state string) bool {
+	switch state {
+	case stateDone, stateFailed, stateCancelled, stateUpdated, stateDrained:
+		return true
+	}
+
+	return false
+}
+
+//writeJSON writes a 200 json response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+//writeError writes a google api error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"message": message,
+			"status":  http.StatusText(status),
+		},
+	})
+}
@@ -7,21 +7,70 @@ type DsJob struct {
 	AppScope    string     `json:"appscope" datastore:"appscope"`
 	JobID       string     `json:"jobid" datastore:"jobid"`
 	JobType     string     `json:"jobtype" datastore:"jobtype"`
-	LastStatus  string     `json:"laststatus" datastore:"laststatus"`
+	LastStatus  JobState   `json:"laststatus" datastore:"laststatus"`
+	StopMode    StopMode   `json:"stopmode,omitempty" datastore:"stopmode"`
+	StopReason  string     `json:"stopreason,omitempty" datastore:"stopreason"`
+	StoppedBy   string     `json:"stoppedby,omitempty" datastore:"stoppedby"`
+	StoppedDate *time.Time `json:"stoppeddate,omitempty" datastore:"stoppeddate"`
 	CreatedDate *time.Time `json:"createddate,omitempty" datastore:"createddate"`
 	LastTouched *time.Time `json:"lasttouched,omitempty" datastore:"lasttouched"`
+	//Imported is set by ImportJobs, it is recorded on the first history row of a new job rather than on the job
+	Imported bool `json:"imported,omitempty" datastore:"imported"`
+}
+
+//StopMode is the way a job was asked to stop
+type StopMode string
+
+const (
+	//StopModeCancel stops the job straight away, discarding any data which is in-flight
+	StopModeCancel StopMode = "cancel"
+	//StopModeDrain stops the job pulling from its input sources and lets it finish processing the data which is in-flight
+	StopModeDrain StopMode = "drain"
+)
+
+//JobHistory is a state observed for a job (or a stop request made against it), from the jobcontrol history
+type JobHistory struct {
+	AppScope   string     `json:"appscope"`
+	JobID      string     `json:"jobid"`
+	JobType    string     `json:"jobtype"`
+	Status     JobState   `json:"status"`
+	StopMode   StopMode   `json:"stopmode,omitempty"`
+	StopReason string     `json:"stopreason,omitempty"`
+	StoppedBy  string     `json:"stoppedby,omitempty"`
+	Imported   bool       `json:"imported,omitempty"`
+	Observed   *time.Time `json:"observed"`
+}
+
+//StopRequest describes a request to stop a job, which is recorded against the job
+type StopRequest struct {
+	Mode        StopMode `json:"mode"`
+	Reason      string   `json:"reason,omitempty"`
+	RequestedBy string   `json:"requestedby,omitempty"`
 }
 
 //JobSimpleMeta contains the basic data
 type JobSimpleMeta struct {
-	JobID        string `json:"jobid"`
-	JobType      string `json:"jobtype"`
-	CurrentState string `json:"currentstate"`
+	JobID        string   `json:"jobid"`
+	JobType      string   `json:"jobtype"`
+	CurrentState JobState `json:"currentstate"`
 }
 
-//JobRunParameter contains the full set of parameters to run a datflow job
+//JobRunParameter contains the full set of parameters to run a datflow job (the legacy job definition format, see JobDefinition)
 type JobRunParameter struct {
+	//TemplateKind is the kind of template the job is launched from, empty for a classic template
+	TemplateKind       TemplateKind      `json:"templatekind,omitempty"`
 	CustomParameters   map[string]string `json:"customparameters"`
 	RuntimeEnvironment map[string]string `json:"runtimeenvironment"`
 	JobRequest         map[string]string `json:"jobrequest"`
 }
+
+//TemplateKind is the kind of template a job is launched from
+type TemplateKind string
+
+//template kinds
+const (
+	//TemplateKindClassic launches a classic template from the jobrequest gcsPath
+	TemplateKindClassic TemplateKind = "classic"
+	//TemplateKindFlex launches a flex template from the jobrequest containerSpecGcsPath
+	TemplateKindFlex TemplateKind = "flex"
+)
@@ -1,9 +1,104 @@
 package dfmgr
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 //errors
 var (
 	//ErrNoDataFound occurs if a json result returns null
 	ErrNoDataFound = errors.New("data was not found for this search")
+	//ErrJobIDConflict occurs if a jobid is saved against a second appscope
+	ErrJobIDConflict = errors.New("jobid is already registered to another appscope")
+	//ErrNegativeLimit occurs if a negative row limit is requested
+	ErrNegativeLimit = errors.New("limit must not be negative")
+	//ErrNoProject occurs if a manager is created without a GCP project
+	ErrNoProject = errors.New("a GCP project is required")
+	//ErrNoRegion occurs if a manager is created without a GCP region
+	ErrNoRegion = errors.New("a GCP region is required")
+	//ErrNoJobStore occurs if a manager is created without a job store
+	ErrNoJobStore = errors.New("a job store is required")
+	//ErrNoJobDefinitionSource occurs if job definitions are requested from a manager which has no source for them
+	ErrNoJobDefinitionSource = errors.New("no job definition source is configured")
+	//ErrInvalidStopMode occurs if a job is asked to stop in a way other than cancel or drain
+	ErrInvalidStopMode = errors.New("stop mode must be cancel or drain")
+	//ErrInvalidTransition occurs if a job is asked to move to a state it cannot reach from its current state (see TransitionError)
+	ErrInvalidTransition = errors.New("invalid job state transition")
+	//ErrUnknownJobState occurs if dataflow returns a job state which is not one of the CnstState* values
+	ErrUnknownJobState = errors.New("unknown job state")
+	//ErrNoJobIDs occurs if a watch is requested without any jobids
+	ErrNoJobIDs = errors.New("at least one jobid is required")
+	//ErrInvalidWindow occurs if statistics are requested for a window which does not end after it starts
+	ErrInvalidWindow = errors.New("window must end after it starts")
+	//ErrNoAppScopes occurs if a reconciler is started without any appscopes
+	ErrNoAppScopes = errors.New("at least one appscope is required")
+	//ErrNoLocker occurs if a reconciler is started without a Locker
+	ErrNoLocker = errors.New("a locker is required")
+	//ErrInvalidImportRule occurs if an import rule does not name an appscope and a jobtype (or a label to read it from)
+	ErrInvalidImportRule = errors.New("an import rule requires an appscope and a jobtype or jobtype label")
+	//ErrInvalidTemplateKind occurs if a job definition targets a template kind other than classic or flex
+	ErrInvalidTemplateKind = errors.New("template kind must be classic or flex")
+	//ErrUnknownRuntimeKey occurs if a job definition's runtimeenvironment contains a key which does not map to a dataflow runtime setting
+	ErrUnknownRuntimeKey = errors.New("unknown runtimeenvironment key")
+	//ErrInvalidRuntimeValue occurs if a job definition's runtimeenvironment value cannot be converted (e.g. a non-numeric maxWorkers)
+	ErrInvalidRuntimeValue = errors.New("invalid runtimeenvironment value")
+	//ErrNoJobDefinition occurs if a nil job definition is supplied
+	ErrNoJobDefinition = errors.New("a job definition is required")
+	//ErrUnsupportedDefinitionVersion occurs if a job definition declares a version this package cannot read
+	ErrUnsupportedDefinitionVersion = errors.New("unsupported job definition version")
 )
+
+//ConfigProblem is a single missing or malformed configuration setting
+type ConfigProblem struct {
+	Setting  string
+	Variable string
+	Reason   string
+}
+
+//ConfigError is returned when the package configuration is invalid, and lists every problem found
+type ConfigError struct {
+	Problems []ConfigProblem
+}
+
+//Error lists the problems in the order they were found
+func (e *ConfigError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, item := range e.Problems {
+		msgs[i] = fmt.Sprintf("%s (%s) %s", item.Variable, item.Setting, item.Reason)
+	}
+
+	return "invalid dfmgr configuration: " + strings.Join(msgs, "; ")
+}
+
+//add records a problem with a setting
+func (e *ConfigError) add(cv configVar, reason string) {
+	e.Problems = append(e.Problems, ConfigProblem{Setting: cv.setting, Variable: cv.variable, Reason: reason})
+}
+
+//DefinitionProblem is a single invalid job definition field
+type DefinitionProblem struct {
+	Field  string
+	Reason string
+}
+
+//DefinitionError is returned when a job definition is invalid, and lists every problem found
+type DefinitionError struct {
+	Problems []DefinitionProblem
+}
+
+//Error lists the problems in the order they were found
+func (e *DefinitionError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, item := range e.Problems {
+		msgs[i] = fmt.Sprintf("%s %s", item.Field, item.Reason)
+	}
+
+	return "invalid job definition: " + strings.Join(msgs, "; ")
+}
+
+//add records a problem with a field
+func (e *DefinitionError) add(field, reason string) {
+	e.Problems = append(e.Problems, DefinitionProblem{Field: field, Reason: reason})
+}
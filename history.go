@@ -0,0 +1,125 @@
+package dfmgr
+
+import (
+	"context"
+	"time"
+)
+
+// JobTimeline is a job's recorded history, as the states it has passed through
+type JobTimeline struct {
+	AppScope string
+	JobID    string
+	JobType  string
+	//Steps are the distinct states of the job, oldest first
+	Steps []*TimelineStep
+	//Created is when the job was first recorded
+	Created time.Time
+	//Started is when the job was first seen running, nil if it never ran
+	Started *time.Time
+	//Finished is when the job was first seen in a terminal state, nil if it has not finished
+	Finished *time.Time
+	//FinalState is the last state recorded for the job
+	FinalState JobState
+}
+
+// TimelineStep is a state in a job's timeline
+type TimelineStep struct {
+	Status  JobState
+	Entered time.Time
+	//Duration is the time spent in the state, zero for the last step
+	Duration time.Duration
+	//StopMode, StopReason and StoppedBy are set on the step which followed a stop request
+	StopMode   StopMode
+	StopReason string
+	StoppedBy  string
+	//Imported is set on the first step of a job added by ImportJobs, as the states before it was imported were not observed
+	Imported bool
+}
+
+// QueueTime is the time between the job being recorded and first running, false if it never ran or was added by ImportJobs,
+// as its time in the queue was not observed
+func (tl *JobTimeline) QueueTime() (time.Duration, bool) {
+	if tl.Started == nil || tl.Steps[0].Imported {
+		return 0, false
+	}
+
+	return tl.Started.Sub(tl.Created), true
+}
+
+// RunTime is the time between the job first running and finishing, false if either has not happened
+func (tl *JobTimeline) RunTime() (time.Duration, bool) {
+	if tl.Started == nil || tl.Finished == nil {
+		return 0, false
+	}
+
+	return tl.Finished.Sub(*tl.Started), true
+}
+
+// GetJobHistory gets the states recorded for a job, oldest first
+func (dfm *DfMgr) GetJobHistory(ctx context.Context, jobID string) (_ []*JobHistory, err error) {
+	op := beginOp(ctx, dfm.log, "GetJobHistory", "jobid", jobID)
+	defer op.end(&err)
+
+	return dfm.ds.GetJobHistory(ctx, jobID)
+}
+
+// GetJobTimeline gets a job's history as a timeline of the states it has passed through
+func (dfm *DfMgr) GetJobTimeline(ctx context.Context, jobID string) (_ *JobTimeline, err error) {
+	op := beginOp(ctx, dfm.log, "GetJobTimeline", "jobid", jobID)
+	defer op.end(&err)
+
+	hist, err := dfm.ds.GetJobHistory(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	//a custom store may return an empty history rather than ErrNoDataFound
+	if len(hist) == 0 {
+		return nil, ErrNoDataFound
+	}
+
+	return newJobTimeline(hist), nil
+}
+
+// newJobTimeline builds a timeline from a job's history, which must be oldest first and not empty
+func newJobTimeline(hist []*JobHistory) *JobTimeline {
+	tl := &JobTimeline{
+		AppScope: hist[0].AppScope,
+		JobID:    hist[0].JobID,
+		JobType:  hist[0].JobType,
+		Created:  *hist[0].Observed,
+	}
+
+	var last *TimelineStep
+	for _, h := range hist {
+		//consecutive observations of the same state are a single step, unless they record a stop request
+		if last == nil || last.Status != h.Status || h.StopMode != "" {
+			if last != nil {
+				last.Duration = h.Observed.Sub(last.Entered)
+			}
+
+			last = &TimelineStep{Status: h.Status, Entered: *h.Observed, Imported: h.Imported}
+			tl.Steps = append(tl.Steps, last)
+		}
+
+		if h.StopMode != "" {
+			last.StopMode = h.StopMode
+			last.StopReason = h.StopReason
+			last.StoppedBy = h.StoppedBy
+		}
+
+		if tl.Started == nil && h.Status == CnstStateRunning {
+			dt := *h.Observed
+			tl.Started = &dt
+		}
+
+		if tl.Finished == nil && h.Status.IsTerminal() {
+			dt := *h.Observed
+			tl.Finished = &dt
+		}
+	}
+
+	tl.FinalState = last.Status
+
+	return tl
+}
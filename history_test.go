@@ -0,0 +1,126 @@
+package dfmgr
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_GetJobTimeline(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, mm := newFakeMgr(ctx, t)
+	fc.Script(CnstStatePending, CnstStatePending, CnstStateRunning)
+
+	now := time.Date(2019, 4, 11, 12, 0, 0, 0, time.UTC)
+	mm.now = func() time.Time { return now }
+	created := now
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//pending is observed twice but recorded once
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Minute)
+
+		if _, err = dfm.GetJobStatus(ctx, meta.JobID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now = now.Add(time.Minute)
+
+	req := &StopRequest{Mode: StopModeCancel, Reason: "superseded", RequestedBy: "ops@example.com"}
+	if _, err = dfm.JobStopRequest(ctx, meta.JobID, req); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Minute)
+
+	if _, err = dfm.GetJobStatus(ctx, meta.JobID); err != nil {
+		t.Fatal(err)
+	}
+
+	hist, err := dfm.GetJobHistory(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hist) != 4 {
+		t.Fatalf("expected 4 history rows, got %d", len(hist))
+	}
+
+	tl, err := dfm.GetJobTimeline(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		status   JobState
+		duration time.Duration
+	}{
+		{CnstStatePending, 2 * time.Minute},
+		{CnstStateRunning, time.Minute},
+		{CnstStateCancelling, time.Minute},
+		{CnstStateCancelled, 0},
+	}
+
+	if len(tl.Steps) != len(want) {
+		t.Fatalf("expected %d steps, got %d", len(want), len(tl.Steps))
+	}
+
+	for i, w := range want {
+		if tl.Steps[i].Status != w.status || tl.Steps[i].Duration != w.duration {
+			t.Fatalf("expected %s for %v at %d, got %v", w.status, w.duration, i, tl.Steps[i])
+		}
+	}
+
+	if tl.Steps[2].StopMode != req.Mode || tl.Steps[2].StopReason != req.Reason || tl.Steps[2].StoppedBy != req.RequestedBy {
+		t.Fatalf("expected the stop request on the cancelling step, got %v", tl.Steps[2])
+	}
+
+	if !tl.Created.Equal(created) || tl.FinalState != CnstStateCancelled || tl.JobID != meta.JobID || tl.AppScope != jbappscope {
+		t.Fatalf("unexpected timeline %v", tl)
+	}
+
+	if d, ok := tl.QueueTime(); !ok || d != 2*time.Minute {
+		t.Fatalf("expected a queue time of 2m, got %v", d)
+	}
+
+	if d, ok := tl.RunTime(); !ok || d != 2*time.Minute {
+		t.Fatalf("expected a run time of 2m, got %v", d)
+	}
+
+	if _, err = dfm.GetJobTimeline(ctx, "654321"); err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+}
+
+//emptyHistoryStore is a job store which returns an empty history rather than ErrNoDataFound
+type emptyHistoryStore struct {
+	*MemMgr
+}
+
+//GetJobHistory returns an empty history
+func (emptyHistoryStore) GetJobHistory(ctx context.Context, jobid string) ([]*JobHistory, error) {
+	return nil, nil
+}
+
+func Test_GetJobTimelineEmpty(t *testing.T) {
+	ctx := context.Background()
+
+	dfm, err := NewMgrWithOptions(ctx,
+		WithDataflowClient(NewFakeDataflowClient()),
+		WithJobStore(emptyHistoryStore{NewMemMgr(ctx)}),
+		WithProject("testproject"),
+		WithRegion("europe-west1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dfm.GetJobTimeline(ctx, "123456"); err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+}
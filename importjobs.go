@@ -0,0 +1,154 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	df "google.golang.org/api/dataflow/v1b3"
+)
+
+// ImportRule maps dataflow jobs which were not launched by this package to an appscope and jobtype. A job matches if its
+// name has the NamePrefix and it carries every one of the Labels (an empty NamePrefix or Labels matches any job).
+type ImportRule struct {
+	NamePrefix string            `json:"nameprefix,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	AppScope   string            `json:"appscope"`
+	//JobType is the jobtype given to matching jobs, unless JobTypeLabel is set and the job has that label
+	JobType string `json:"jobtype,omitempty"`
+	//JobTypeLabel names a label whose value is used as the jobtype
+	JobTypeLabel string `json:"jobtypelabel,omitempty"`
+}
+
+// ImportOptions controls ImportJobs
+type ImportOptions struct {
+	//Rules are tried in order, and the first which matches a job maps it
+	Rules []ImportRule
+	//IncludeTerminal also imports untracked jobs which have reached a terminal state. The jobs list returns finished jobs for up to
+	//30 days, and an imported job is recorded as created at the time of the import, so these would appear as recent jobs.
+	IncludeTerminal bool
+}
+
+// ImportReport is the outcome of ImportJobs
+type ImportReport struct {
+	//Imported are the jobs which were not previously tracked
+	Imported []*DsJob
+	//Updated are the jobs which were already tracked, they keep their appscope and jobtype and have their status refreshed
+	Updated []*DsJob
+	//Unmatched are the untracked jobs which no rule matched
+	Unmatched []*df.Job
+	//Failed are the jobs which could not be recorded, with the error
+	Failed []JobEvent
+}
+
+// validate checks that the rule maps to an appscope and a jobtype
+func (ir *ImportRule) validate() error {
+	if ir.AppScope == "" || (ir.JobType == "" && ir.JobTypeLabel == "") {
+		return ErrInvalidImportRule
+	}
+
+	return nil
+}
+
+// match returns the jobtype for a job, false if the rule does not match it (or no jobtype can be found for it)
+func (ir *ImportRule) match(jb *df.Job) (string, bool) {
+	if !strings.HasPrefix(jb.Name, ir.NamePrefix) {
+		return "", false
+	}
+
+	for k, v := range ir.Labels {
+		if lv, ok := jb.Labels[k]; !ok || lv != v {
+			return "", false
+		}
+	}
+
+	if ir.JobTypeLabel != "" {
+		if lv := jb.Labels[ir.JobTypeLabel]; lv != "" {
+			return lv, true
+		}
+	}
+
+	return ir.JobType, ir.JobType != ""
+}
+
+// ImportJobs lists the jobs in the project region and records them in the job store, so that jobs launched elsewhere
+// (console, gcloud, other tools) are tracked. Jobs which are already tracked are refreshed, others are mapped by the rules.
+// Untracked jobs are only imported while they are active, unless im.IncludeTerminal is set.
+func (dfm *DfMgr) ImportJobs(ctx context.Context, im *ImportOptions) (_ *ImportReport, err error) {
+	var o ImportOptions
+	if im != nil {
+		o = *im
+	}
+
+	op := beginOp(ctx, dfm.log, "ImportJobs", "rules", len(o.Rules), "includeterminal", o.IncludeTerminal)
+	defer op.end(&err)
+
+	for i := range o.Rules {
+		if err = o.Rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+
+	jbs, err := dfm.dfc.ListJobs(ctx, dfm.project, dfm.region)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{}
+
+	for _, jb := range jbs {
+		state, err := ParseJobState(jb.CurrentState)
+		if err != nil {
+			report.Failed = append(report.Failed, JobEvent{JobID: jb.Id, Job: jb, Err: err})
+			continue
+		}
+
+		//tracked jobs keep their mapping
+		dsjb, err := dfm.ds.GetJob(ctx, jb.Id)
+		if err != nil && !errors.Is(err, ErrNoDataFound) {
+			report.Failed = append(report.Failed, JobEvent{JobID: jb.Id, To: state, Job: jb, Err: err})
+			continue
+		}
+
+		tracked := err == nil
+
+		if !tracked {
+			if !o.IncludeTerminal && !state.IsActive() {
+				continue
+			}
+
+			dsjb = &DsJob{JobID: jb.Id, Imported: true}
+
+			for i := range o.Rules {
+				if jobtype, ok := o.Rules[i].match(jb); ok {
+					dsjb.AppScope = o.Rules[i].AppScope
+					dsjb.JobType = jobtype
+					break
+				}
+			}
+
+			if dsjb.AppScope == "" {
+				report.Unmatched = append(report.Unmatched, jb)
+				continue
+			}
+		}
+
+		dsjb.LastStatus = state
+
+		if err = dfm.ds.SaveJob(ctx, dsjb); err != nil {
+			report.Failed = append(report.Failed, JobEvent{JobID: jb.Id, To: state, Job: jb, Err: err})
+			continue
+		}
+
+		if tracked {
+			report.Updated = append(report.Updated, dsjb)
+		} else {
+			report.Imported = append(report.Imported, dsjb)
+		}
+	}
+
+	op.add("listed", len(jbs), "imported", len(report.Imported), "updated", len(report.Updated), "unmatched", len(report.Unmatched), "failed", len(report.Failed))
+
+	return report, nil
+}
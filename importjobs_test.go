@@ -0,0 +1,107 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	df "google.golang.org/api/dataflow/v1b3"
+)
+
+func Test_ImportJobs(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, mm := newFakeMgr(ctx, t)
+	fc.Script(CnstStateRunning)
+
+	//a job launched through the package is already tracked
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fc.AddJob(&df.Job{Id: "console1", Name: "nightly-orders", CurrentState: CnstStateRunning})
+	fc.AddJob(&df.Job{Id: "gcloud1", Name: "adhoc", CurrentState: CnstStatePending, Labels: map[string]string{"team": "etl", "pipeline": "customers"}})
+	fc.AddJob(&df.Job{Id: "gcloud2", Name: "adhoc", CurrentState: CnstStateDone, Labels: map[string]string{"team": "etl"}})
+	fc.AddJob(&df.Job{Id: "other1", Name: "someone-elses", CurrentState: CnstStateRunning})
+	fc.AddJob(&df.Job{Id: "bad1", Name: "nightly-bad", CurrentState: "JOB_STATE_SOMETHING_NEW"})
+
+	rules := []ImportRule{
+		{NamePrefix: "nightly-", AppScope: "importapp", JobType: "nightly"},
+		{Labels: map[string]string{"team": "etl"}, AppScope: "importapp", JobType: "etl", JobTypeLabel: "pipeline"},
+	}
+
+	report, err := dfm.ImportJobs(ctx, &ImportOptions{Rules: rules, IncludeTerminal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"console1": "nightly", "gcloud1": "customers", "gcloud2": "etl"}
+	if len(report.Imported) != len(want) {
+		t.Fatalf("expected %v, got %v", want, report.Imported)
+	}
+
+	for _, item := range report.Imported {
+		jb, err := mm.GetJob(ctx, item.JobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if jb.AppScope != "importapp" || jb.JobType != want[item.JobID] {
+			t.Fatalf("expected %s to be imported as %s, got %v", item.JobID, want[item.JobID], jb)
+		}
+	}
+
+	if len(report.Updated) != 1 || report.Updated[0].JobID != meta.JobID || report.Updated[0].AppScope != jbappscope {
+		t.Fatalf("expected the tracked job to keep its appscope, got %v", report.Updated)
+	}
+
+	if len(report.Unmatched) != 1 || report.Unmatched[0].Id != "other1" {
+		t.Fatalf("expected other1 to be unmatched, got %v", report.Unmatched)
+	}
+
+	if len(report.Failed) != 1 || report.Failed[0].JobID != "bad1" || !errors.Is(report.Failed[0].Err, ErrUnknownJobState) {
+		t.Fatalf("expected bad1 to fail, got %v", report.Failed)
+	}
+
+	//the time an imported job spent in the queue before it was recorded is unknown, whatever state it was imported in
+	if err = mm.SetJobStatus(ctx, "gcloud1", CnstStateRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, jobid := range []string{"console1", "gcloud1"} {
+		tl, err := dfm.GetJobTimeline(ctx, jobid)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !tl.Steps[0].Imported || tl.Started == nil {
+			t.Fatalf("expected %s to be imported and running, got %v", jobid, tl)
+		}
+
+		if _, ok := tl.QueueTime(); ok {
+			t.Fatalf("expected no queue time for imported job %s, got %v", jobid, tl)
+		}
+	}
+
+	//terminal jobs are skipped by default
+	fc.AddJob(&df.Job{Id: "console2", Name: "nightly-orders", CurrentState: CnstStateFailed})
+
+	report, err = dfm.ImportJobs(ctx, &ImportOptions{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Imported) != 0 {
+		t.Fatalf("expected no imports, got %v", report.Imported)
+	}
+
+	if _, err = mm.GetJob(ctx, "console2"); err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+
+	//rules must map to an appscope and jobtype
+	_, err = dfm.ImportJobs(ctx, &ImportOptions{Rules: []ImportRule{{NamePrefix: "nightly-", AppScope: "importapp"}}})
+	if !errors.Is(err, ErrInvalidImportRule) {
+		t.Fatalf("expected %v, got %v", ErrInvalidImportRule, err)
+	}
+}
@@ -0,0 +1,200 @@
+package dfmgr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// JobDefinitionVersion is the version of the typed job definition format. Definitions without a version are read as the
+// legacy JobRunParameter format (version 1)
+const JobDefinitionVersion = 2
+
+// JobDefinition is a typed job definition, which is read from json with unknown fields rejected
+type JobDefinition struct {
+	//Version is JobDefinitionVersion, a definition built in code may leave it zero
+	Version      int          `json:"version"`
+	TemplateKind TemplateKind `json:"templatekind,omitempty"`
+	//JobName is the job name, with a %s which is replaced by the launch timestamp
+	JobName string `json:"jobname"`
+	JobType string `json:"jobtype,omitempty"`
+	//Location is the region the job runs in, which is required in both formats
+	Location string `json:"location"`
+	//TemplatePath is the gcs path of the classic template, or of the flex template container spec
+	TemplatePath string            `json:"templatepath"`
+	Parameters   map[string]string `json:"parameters,omitempty"`
+	Environment  RuntimeSettings   `json:"environment"`
+}
+
+// ParseJobDefinition reads a job definition in either the typed format or the legacy JobRunParameter format
+func ParseJobDefinition(data []byte) (*JobDefinition, error) {
+	version, err := definitionVersion(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case 1:
+		var param JobRunParameter
+		if err := json.Unmarshal(data, &param); err != nil {
+			return nil, err
+		}
+
+		return param.Definition()
+	case JobDefinitionVersion:
+		var jd JobDefinition
+
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+
+		if err := dec.Decode(&jd); err != nil {
+			return nil, err
+		}
+
+		return &jd, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedDefinitionVersion, version)
+	}
+}
+
+// definitionVersion reads the version of a json job definition, 1 if it has none
+func definitionVersion(data []byte) (int, error) {
+	var probe struct {
+		Version *int `json:"version"`
+	}
+
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return 0, err
+	}
+
+	if probe.Version == nil {
+		return 1, nil
+	}
+
+	return *probe.Version, nil
+}
+
+// Definition converts a legacy job definition to the typed format, the runtimeenvironment values are parsed and unknown keys are rejected
+func (jp *JobRunParameter) Definition() (*JobDefinition, error) {
+	if jp == nil {
+		return nil, ErrNoJobDefinition
+	}
+
+	switch jp.TemplateKind {
+	case "", TemplateKindClassic, TemplateKindFlex:
+	default:
+		return nil, ErrInvalidTemplateKind
+	}
+
+	rs, err := newRuntimeSettings(jp.RuntimeEnvironment)
+	if err != nil {
+		return nil, err
+	}
+
+	return jp.definition(rs), nil
+}
+
+// definition converts a legacy job definition to the typed format, with runtime settings which have already been converted
+func (jp *JobRunParameter) definition(rs *RuntimeSettings) *JobDefinition {
+	return &JobDefinition{
+		Version:      JobDefinitionVersion,
+		TemplateKind: jp.TemplateKind,
+		JobName:      jp.JobRequest["jobName"],
+		JobType:      jp.JobRequest["jobType"],
+		Location:     jp.JobRequest["location"],
+		TemplatePath: jp.JobRequest[jp.templatePathKey()],
+		Parameters:   jp.CustomParameters,
+		Environment:  *rs,
+	}
+}
+
+// templatePathKey is the jobrequest key which holds the template path for the template kind
+func (jp *JobRunParameter) templatePathKey() string {
+	if jp.TemplateKind == TemplateKindFlex {
+		return "containerSpecGcsPath"
+	}
+
+	return "gcsPath"
+}
+
+// RunParameter converts the definition to the legacy format, empty optional fields are left out of the jobrequest
+func (jd *JobDefinition) RunParameter() *JobRunParameter {
+	jp := &JobRunParameter{
+		TemplateKind:       jd.TemplateKind,
+		CustomParameters:   jd.Parameters,
+		RuntimeEnvironment: jd.Environment.runtimeMap(),
+		JobRequest: map[string]string{
+			"jobName": jd.JobName,
+		},
+	}
+
+	if jd.JobType != "" {
+		jp.JobRequest["jobType"] = jd.JobType
+	}
+
+	if jd.Location != "" {
+		jp.JobRequest["location"] = jd.Location
+	}
+
+	if jd.TemplateKind == TemplateKindFlex {
+		jp.JobRequest["containerSpecGcsPath"] = jd.TemplatePath
+	} else {
+		jp.JobRequest["gcsPath"] = jd.TemplatePath
+	}
+
+	return jp
+}
+
+// Validate checks the definition can be launched in region (which is not checked if empty), returning a *DefinitionError which
+// lists every problem
+func (jd *JobDefinition) Validate(region string) error {
+	de := &DefinitionError{}
+
+	jd.validate(region, de)
+
+	if len(de.Problems) > 0 {
+		return de
+	}
+
+	return nil
+}
+
+// validate adds the definition's problems to de
+func (jd *JobDefinition) validate(region string, de *DefinitionError) {
+	if jd.Version != 0 && jd.Version != JobDefinitionVersion {
+		de.add("version", fmt.Sprintf("must be %d", JobDefinitionVersion))
+	}
+
+	switch jd.TemplateKind {
+	case "", TemplateKindClassic, TemplateKindFlex:
+	default:
+		de.add("templatekind", "must be classic or flex")
+	}
+
+	if reason := jobNameProblem(jd.JobName); reason != "" {
+		de.add("jobname", reason)
+	}
+
+	if reason := gcsURIProblem(jd.TemplatePath); reason != "" {
+		de.add("templatepath", reason)
+	}
+
+	switch {
+	case jd.Location == "":
+		de.add("location", "is required")
+	case region != "" && jd.Location != region:
+		de.add("location", fmt.Sprintf("%s does not match the configured region %s", jd.Location, region))
+	}
+
+	if jd.Environment.MaxWorkers < 0 {
+		de.add("environment.maxWorkers", "must not be negative")
+	}
+
+	if jd.Environment.NumWorkers < 0 {
+		de.add("environment.numWorkers", "must not be negative")
+	}
+
+	if jd.Environment.MaxWorkers > 0 && jd.Environment.NumWorkers > jd.Environment.MaxWorkers {
+		de.add("environment.numWorkers", fmt.Sprintf("must not exceed maxWorkers (%d)", jd.Environment.MaxWorkers))
+	}
+}
@@ -0,0 +1,216 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_ParseJobDefinition(t *testing.T) {
+	//the legacy format
+	data, err := os.ReadFile("jobdef/dataflowjobdef.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jd, err := ParseJobDefinition(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jd.Version != JobDefinitionVersion || jd.JobName != "dflauncher%s" || jd.JobType != "df-etl" || jd.Environment.MaxWorkers != 1 || jd.Parameters["runner"] != "DataflowRunner" {
+		t.Fatalf("unexpected definition %+v", jd)
+	}
+
+	if jd.TemplatePath != "gs://{{project}}/dataflow/templates/{{dataflowtemplatename}}" {
+		t.Fatalf("unexpected template path %s", jd.TemplatePath)
+	}
+
+	//the typed format
+	data, err = os.ReadFile("jobdef/typedjobdef.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jd, err = ParseJobDefinition(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jd.TemplateKind != TemplateKindClassic || jd.Environment.MaxWorkers != 2 || jd.Environment.AdditionalUserLabels["team"] != "etl" {
+		t.Fatalf("unexpected definition %+v", jd)
+	}
+
+	//converting to the legacy format and back is lossless
+	cp, err := jd.RunParameter().Definition()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(cp, jd) {
+		t.Fatalf("expected %+v, got %+v", jd, cp)
+	}
+
+	tests := []struct {
+		data string
+		want error
+	}{
+		{`{"version": 3, "jobname": "x%s"}`, ErrUnsupportedDefinitionVersion},
+		{`{"runtimeenvironment": {"maxWorkers": "lots"}}`, ErrInvalidRuntimeValue},
+		{`{"templatekind": "sql"}`, ErrInvalidTemplateKind},
+	}
+
+	for _, tt := range tests {
+		if _, err = ParseJobDefinition([]byte(tt.data)); !errors.Is(err, tt.want) {
+			t.Fatalf("expected %v for %s, got %v", tt.want, tt.data, err)
+		}
+	}
+
+	//typos in the typed format are rejected
+	if _, err = ParseJobDefinition([]byte(`{"version": 2, "jobname": "x%s", "enviroment": {}}`)); err == nil {
+		t.Fatal("expected an unknown field error")
+	}
+}
+func Test_JobDefinitionValidate(t *testing.T) {
+	jd := &JobDefinition{
+		Version:      JobDefinitionVersion,
+		TemplateKind: "sql",
+		Environment:  RuntimeSettings{NumWorkers: -1},
+	}
+
+	err := jd.Validate("")
+
+	var de *DefinitionError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected a *DefinitionError, got %v", err)
+	}
+
+	want := []string{"templatekind", "jobname", "templatepath", "location", "environment.numWorkers"}
+	if len(de.Problems) != len(want) {
+		t.Fatalf("expected problems with %v, got %v", want, de.Problems)
+	}
+
+	for i, item := range de.Problems {
+		if item.Field != want[i] {
+			t.Fatalf("expected %s at %d, got %s", want[i], i, item.Field)
+		}
+	}
+
+	jd = &JobDefinition{Version: JobDefinitionVersion, JobName: "dflauncher%s", TemplatePath: "gs://testproject/dataflow/templates/test", Location: "europe-west1"}
+	if err = jd.Validate("europe-west1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = jd.Validate("us-central1"); !errors.As(err, &de) || len(de.Problems) != 1 || de.Problems[0].Field != "location" {
+		t.Fatalf("expected a location problem, got %v", err)
+	}
+
+	//optional fields which are empty are left out of the legacy format, which requires a location as the typed format does
+	jd.Location = ""
+
+	param := jd.RunParameter()
+	if _, ok := param.JobRequest["jobType"]; ok {
+		t.Fatalf("unexpected jobType in %v", param.JobRequest)
+	}
+
+	if err = param.Validate(""); !errors.As(err, &de) || len(de.Problems) != 1 || de.Problems[0].Field != "jobrequest.location" {
+		t.Fatalf("expected a location problem, got %v", err)
+	}
+}
+func Test_JobDefinitionRoundTrip(t *testing.T) {
+	//a definition built in code may leave the version unset
+	jd := &JobDefinition{
+		JobName:      "dflauncher%s",
+		Location:     "europe-west1",
+		TemplatePath: "gs://testproject/dataflow/templates/test",
+		Parameters:   map[string]string{"filter": "region=emea,status=open"},
+		Environment: RuntimeSettings{
+			MaxWorkers:            2,
+			AdditionalExperiments: []string{"shuffle_mode=service", `a,b\c`},
+			AdditionalUserLabels:  map[string]string{"team": "etl,ops", "owner=": `x=\y`},
+		},
+	}
+
+	if err := jd.Validate("europe-west1"); err != nil {
+		t.Fatal(err)
+	}
+
+	//values containing the separators survive the legacy runtimeenvironment encoding
+	back, err := jd.RunParameter().Definition()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jd.Version = JobDefinitionVersion
+
+	if !reflect.DeepEqual(back, jd) {
+		t.Fatalf("expected %+v, got %+v", jd, back)
+	}
+}
+func Test_JobStartDefinition(t *testing.T) {
+	ctx := context.Background()
+	fc := NewFakeDataflowClient()
+
+	dfm, err := NewMgrWithOptions(ctx,
+		WithDataflowClient(fc),
+		WithJobStore(NewMemMgr(ctx)),
+		WithJobDefinitionSource(NewDirJobDefinitionSource("jobdef")),
+		WithProject("testproject"),
+		WithRegion("europe-west1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//typed definitions can be read from a source in either format
+	jd, err := dfm.GetJobDefinition(ctx, "typedjobdef.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	param, err := dfm.GetGcsJobDefinition(ctx, "typedjobdef.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if param.RuntimeEnvironment["maxWorkers"] != "2" || param.JobRequest["gcsPath"] != jd.TemplatePath {
+		t.Fatalf("unexpected legacy definition %v", param)
+	}
+
+	meta, err := dfm.JobStartDefinition(ctx, jbappscope, jobtype, jd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if meta.JobType != "df-etl" {
+		t.Fatalf("expected df-etl, got %s", meta.JobType)
+	}
+
+	launched := fc.Launched()
+	if len(launched) != 1 || launched[0].Environment.MaxWorkers != 2 || launched[0].Environment.AdditionalUserLabels["team"] != "etl" || launched[0].GcsPath != jd.TemplatePath {
+		t.Fatalf("unexpected launch request %v", launched)
+	}
+
+	//invalid definitions are not launched
+	jd.JobName = ""
+
+	var de *DefinitionError
+	if _, err = dfm.JobStartDefinition(ctx, jbappscope, jobtype, jd); !errors.As(err, &de) {
+		t.Fatalf("expected a *DefinitionError, got %v", err)
+	}
+
+	if len(fc.Launched()) != 1 {
+		t.Fatal("expected no further launches")
+	}
+
+	//nil definitions are rejected
+	if _, err = dfm.JobStartDefinition(ctx, jbappscope, jobtype, nil); err != ErrNoJobDefinition {
+		t.Fatalf("expected %v, got %v", ErrNoJobDefinition, err)
+	}
+
+	if _, err = dfm.JobStart(ctx, jbappscope, jobtype, nil); err != ErrNoJobDefinition {
+		t.Fatalf("expected %v, got %v", ErrNoJobDefinition, err)
+	}
+}
@@ -0,0 +1,114 @@
+package dfmgr
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	sto "github.com/lidstromberg/storage"
+)
+
+// JobDefinitionSource defines where job run parameters are read from and written to
+type JobDefinitionSource interface {
+	GetJobDefinition(ctx context.Context, name string) (*JobRunParameter, error)
+	SetJobDefinition(ctx context.Context, name string, jd *JobRunParameter) error
+}
+
+// JobDefinitionSource implementations
+var (
+	_ JobDefinitionSource = (*gcsJobDefinitionSource)(nil)
+	_ JobDefinitionSource = (*dirJobDefinitionSource)(nil)
+)
+
+// gcsJobDefinitionSource reads job definitions from a GCS bucket
+type gcsJobDefinitionSource struct {
+	st     *sto.StorMgr
+	bucket string
+}
+
+// NewGcsJobDefinitionSource returns a JobDefinitionSource for the json files in a GCS bucket
+func NewGcsJobDefinitionSource(st *sto.StorMgr, bucket string) JobDefinitionSource {
+	return &gcsJobDefinitionSource{st: st, bucket: bucket}
+}
+
+// GetJobDefinition reads a job definition from the bucket
+func (src *gcsJobDefinitionSource) GetJobDefinition(ctx context.Context, name string) (*JobRunParameter, error) {
+	data, err := src.st.GetBucketFileData(ctx, src.bucket, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalJobDefinition(data)
+}
+
+// SetJobDefinition writes a job definition to the bucket
+func (src *gcsJobDefinitionSource) SetJobDefinition(ctx context.Context, name string, jd *JobRunParameter) error {
+	data, err := json.Marshal(jd)
+	if err != nil {
+		return err
+	}
+
+	return src.st.WriteBucketFile(ctx, src.bucket, name, data)
+}
+
+// dirJobDefinitionSource reads job definitions from a local directory
+type dirJobDefinitionSource struct {
+	dir string
+}
+
+// NewDirJobDefinitionSource returns a JobDefinitionSource for the json files in a local directory (e.g. jobdef/)
+func NewDirJobDefinitionSource(dir string) JobDefinitionSource {
+	return &dirJobDefinitionSource{dir: dir}
+}
+
+// GetJobDefinition reads a job definition from the directory
+func (src *dirJobDefinitionSource) GetJobDefinition(ctx context.Context, name string) (*JobRunParameter, error) {
+	data, err := os.ReadFile(filepath.Join(src.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalJobDefinition(data)
+}
+
+// SetJobDefinition writes a job definition to the directory
+func (src *dirJobDefinitionSource) SetJobDefinition(ctx context.Context, name string, jd *JobRunParameter) error {
+	data, err := json.MarshalIndent(jd, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(src.dir, filepath.FromSlash(name))
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// unmarshalJobDefinition converts json bytes into a job definition, typed definitions (see ParseJobDefinition) are converted to the legacy format
+func unmarshalJobDefinition(data []byte) (*JobRunParameter, error) {
+	version, err := definitionVersion(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != 1 {
+		jd, err := ParseJobDefinition(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return jd.RunParameter(), nil
+	}
+
+	var param *JobRunParameter
+
+	err = json.Unmarshal(data, &param)
+	if err != nil {
+		return nil, err
+	}
+
+	return param, nil
+}
@@ -0,0 +1,71 @@
+package dfmgr
+
+import "fmt"
+
+// jobStates are the known job states
+var jobStates = map[JobState]bool{
+	CnstStateUnknown:            true,
+	CnstStateStopped:            true,
+	CnstStateRunning:            true,
+	CnstStateDone:               true,
+	CnstStateFailed:             true,
+	CnstStateCancelled:          true,
+	CnstStateUpdated:            true,
+	CnstStateDraining:           true,
+	CnstStateDrained:            true,
+	CnstStatePending:            true,
+	CnstStateCancelling:         true,
+	CnstStateQueued:             true,
+	CnstStateResourceCleaningUp: true,
+}
+
+// ParseJobState validates a job state read from dataflow, returning ErrUnknownJobState if it is not one of the CnstState* values
+//
+// An empty state is CnstStateUnknown, as the api omits the field when the state is not specified.
+func ParseJobState(s string) (JobState, error) {
+	if s == "" {
+		return CnstStateUnknown, nil
+	}
+
+	st := JobState(s)
+	if !jobStates[st] {
+		return CnstStateUnknown, fmt.Errorf("%w: %q", ErrUnknownJobState, s)
+	}
+
+	return st, nil
+}
+
+// String returns the dataflow name of the state
+func (st JobState) String() string {
+	return string(st)
+}
+
+// IsValid reports whether the state is one of the CnstState* values
+func (st JobState) IsValid() bool {
+	return jobStates[st]
+}
+
+// IsTerminal reports whether a job in this state has stopped and can no longer change state
+func (st JobState) IsTerminal() bool {
+	switch st {
+	case CnstStateDone, CnstStateFailed, CnstStateCancelled, CnstStateUpdated, CnstStateDrained:
+		return true
+	}
+
+	return false
+}
+
+// IsActive reports whether a job in this state is still in progress (a known state which is not terminal)
+func (st JobState) IsActive() bool {
+	return st.IsValid() && st != CnstStateUnknown && !st.IsTerminal()
+}
+
+// CanCancel reports whether a job in this state may be cancelled
+func (st JobState) CanCancel() bool {
+	return canRequestState(st, CnstStateCancelled)
+}
+
+// CanDrain reports whether a job in this state may be drained
+func (st JobState) CanDrain() bool {
+	return canRequestState(st, CnstStateDrained)
+}
@@ -0,0 +1,102 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_ParseJobState(t *testing.T) {
+	for st := range jobStates {
+		got, err := ParseJobState(string(st))
+		if err != nil || got != st {
+			t.Fatalf("expected %s, got %s (%v)", st, got, err)
+		}
+	}
+
+	//the api omits unspecified states
+	if got, err := ParseJobState(""); err != nil || got != CnstStateUnknown {
+		t.Fatalf("expected %s, got %s (%v)", CnstStateUnknown, got, err)
+	}
+
+	if _, err := ParseJobState("JOB_STATE_PAUSED"); !errors.Is(err, ErrUnknownJobState) {
+		t.Fatalf("expected %v, got %v", ErrUnknownJobState, err)
+	}
+}
+func Test_JobStateClassification(t *testing.T) {
+	tests := []struct {
+		state                           JobState
+		terminal, active, cancel, drain bool
+	}{
+		{CnstStateUnknown, false, false, false, false},
+		{CnstStateStopped, false, true, true, false},
+		{CnstStateQueued, false, true, true, false},
+		{CnstStatePending, false, true, true, false},
+		{CnstStateRunning, false, true, true, true},
+		{CnstStateDraining, false, true, true, false},
+		{CnstStateCancelling, false, true, false, false},
+		{CnstStateResourceCleaningUp, false, true, false, false},
+		{CnstStateDone, true, false, false, false},
+		{CnstStateFailed, true, false, false, false},
+		{CnstStateCancelled, true, false, false, false},
+		{CnstStateUpdated, true, false, false, false},
+		{CnstStateDrained, true, false, false, false},
+		{"JOB_STATE_PAUSED", false, false, false, false},
+	}
+
+	for _, tt := range tests {
+		if tt.state.IsTerminal() != tt.terminal || tt.state.IsActive() != tt.active || tt.state.CanCancel() != tt.cancel || tt.state.CanDrain() != tt.drain {
+			t.Fatalf("unexpected classification for %s", tt.state)
+		}
+	}
+}
+func Test_GetActiveJobs(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, _ := newFakeMgr(ctx, t)
+
+	for _, st := range []JobState{CnstStateRunning, CnstStateDone, CnstStatePending} {
+		fc.Script(st)
+
+		if _, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jbs, err := dfm.GetActiveJobs(ctx, jbappscope, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jbs) != 2 || jbs[0].LastStatus != CnstStateRunning || jbs[1].LastStatus != CnstStatePending {
+		t.Fatalf("unexpected active jobs %v", jbs)
+	}
+
+	if _, err = dfm.GetActiveJobs(ctx, "otherapp", ""); err != ErrNoDataFound {
+		t.Fatalf("expected %v, got %v", ErrNoDataFound, err)
+	}
+}
+func Test_JobStartUnknownState(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, mm := newFakeMgr(ctx, t)
+
+	fc.Script("JOB_STATE_PAUSED")
+
+	//the job is launched, so is recorded even though its state is not recognised
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if !errors.Is(err, ErrUnknownJobState) {
+		t.Fatalf("expected %v, got %v", ErrUnknownJobState, err)
+	}
+
+	if meta == nil {
+		t.Fatal("expected the job meta to be returned with the error")
+	}
+
+	ds, err := mm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.LastStatus != CnstStateUnknown || len(fc.Launched()) != 1 {
+		t.Fatalf("unexpected job %v", ds)
+	}
+}
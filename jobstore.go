@@ -0,0 +1,28 @@
+package dfmgr
+
+import (
+	"context"
+	"time"
+)
+
+// JobStore defines the operations served by a jobcontrol data repo
+type JobStore interface {
+	SaveJob(ctx context.Context, mdp *DsJob) error
+	SetJobStatus(ctx context.Context, jobid string, jobstate JobState) error
+	SetJobStop(ctx context.Context, jobid string, jobstate JobState, req *StopRequest) error
+	GetJob(ctx context.Context, jobid string) (*DsJob, error)
+	GetJobHistory(ctx context.Context, jobid string) ([]*JobHistory, error)
+	GetAppScopeJobHistory(ctx context.Context, appscope, jobtype string, from, to time.Time) ([]*JobHistory, error)
+	GetAppScopeJobs(ctx context.Context, appscope, jobtype string, jobstate JobState) ([]*DsJob, error)
+	GetLatestAppScopeJob(ctx context.Context, appscope, jobtype string, limit int) ([]*DsJob, error)
+	GetAppScopeJobCount(ctx context.Context, appscope, jobtype string, jobstate JobState) (int64, error)
+	DeleteJob(ctx context.Context, appscope, jobid string) error
+	DeleteJobArchive(ctx context.Context, appscope string) error
+}
+
+//JobStore implementations
+var (
+	_ JobStore = (*PgMgr)(nil)
+	_ JobStore = (*MemMgr)(nil)
+	_ JobStore = (*SqliteMgr)(nil)
+)
@@ -0,0 +1,137 @@
+package dfmgr
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Locker elects a single leader among replicas, so that only one of them runs the reconciler at a time
+type Locker interface {
+	//TryLock takes the lock if it is free, returning false if another replica holds it. It is called repeatedly by the leader,
+	//and returns true while the lock is still held (or false if it has been lost, e.g. the db session ended)
+	TryLock(ctx context.Context) (bool, error)
+	//Unlock releases the lock if it is held
+	Unlock(ctx context.Context) error
+}
+
+// Locker implementations
+var (
+	_ Locker = (*PgLocker)(nil)
+	_ Locker = (*localLocker)(nil)
+)
+
+// PgLocker is a Locker which holds a postgres session level advisory lock on a dedicated connection
+type PgLocker struct {
+	mu   sync.Mutex
+	db   *sql.DB
+	key  int64
+	conn *sql.Conn
+}
+
+// NewPgLocker returns a Locker for the advisory lock key, every replica must use the same key
+func NewPgLocker(db *sql.DB, key int64) *PgLocker {
+	return &PgLocker{db: db, key: key}
+}
+
+// TryLock takes the advisory lock, or checks the session which holds it is still alive
+func (pl *PgLocker) TryLock(ctx context.Context) (bool, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	//the lock lasts as long as the session, so a dead connection means it has been lost
+	if pl.conn != nil {
+		if err := pl.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+
+		pl.conn.Close()
+		pl.conn = nil
+
+		return false, nil
+	}
+
+	conn, err := pl.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+	if err = conn.QueryRowContext(ctx, "select pg_try_advisory_lock($1)", pl.key).Scan(&ok); err != nil {
+		conn.Close()
+		return false, err
+	}
+
+	if !ok {
+		conn.Close()
+		return false, nil
+	}
+
+	pl.conn = conn
+
+	return true, nil
+}
+
+// Unlock releases the advisory lock and its connection
+func (pl *PgLocker) Unlock(ctx context.Context) error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	if pl.conn == nil {
+		return nil
+	}
+
+	defer func() {
+		pl.conn.Close()
+		pl.conn = nil
+	}()
+
+	_, err := pl.conn.ExecContext(ctx, "select pg_advisory_unlock($1)", pl.key)
+
+	return err
+}
+
+// LocalLock is an in-process lock, for a single replica or for tests. Each Locker it returns competes for it
+type LocalLock struct {
+	mu     sync.Mutex
+	holder *localLocker
+}
+
+// NewLocalLock returns a free LocalLock
+func NewLocalLock() *LocalLock {
+	return &LocalLock{}
+}
+
+// Locker returns a new contender for the lock
+func (ll *LocalLock) Locker() Locker {
+	return &localLocker{lock: ll}
+}
+
+// localLocker is a contender for a LocalLock
+type localLocker struct {
+	lock *LocalLock
+}
+
+// TryLock takes the lock if it is free or already held by this contender
+func (lk *localLocker) TryLock(ctx context.Context) (bool, error) {
+	lk.lock.mu.Lock()
+	defer lk.lock.mu.Unlock()
+
+	if lk.lock.holder == nil {
+		lk.lock.holder = lk
+	}
+
+	return lk.lock.holder == lk, nil
+}
+
+// Unlock frees the lock if this contender holds it
+func (lk *localLocker) Unlock(ctx context.Context) error {
+	lk.lock.mu.Lock()
+	defer lk.lock.mu.Unlock()
+
+	if lk.lock.holder == lk {
+		lk.lock.holder = nil
+	}
+
+	return nil
+}
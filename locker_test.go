@@ -0,0 +1,47 @@
+package dfmgr
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_LocalLock(t *testing.T) {
+	ctx := context.Background()
+	ll := NewLocalLock()
+	a, b := ll.Locker(), ll.Locker()
+
+	tests := []struct {
+		lk     Locker
+		unlock bool
+		want   bool
+	}{
+		{a, false, true},
+		{b, false, false},
+		//the holder keeps the lock
+		{a, false, true},
+		//a contender which does not hold the lock cannot free it
+		{b, true, false},
+		{a, false, true},
+		{a, true, false},
+		{b, false, true},
+		{a, false, false},
+	}
+
+	for i, tt := range tests {
+		if tt.unlock {
+			if err := tt.lk.Unlock(ctx); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		got, err := tt.lk.TryLock(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.want {
+			t.Fatalf("expected %v at %d, got %v", tt.want, i, got)
+		}
+	}
+}
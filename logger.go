@@ -0,0 +1,73 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+)
+
+//newLogger returns the default manager logger, which is silent unless debug is on (when it writes text to stdout, including debug events)
+func newLogger(debug bool) *slog.Logger {
+	if !debug {
+		return slog.New(slog.DiscardHandler)
+	}
+
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+//optionLogger returns the logger supplied through WithLogger, or the default logger for the debug setting
+func optionLogger(debug bool, opts []Option) *slog.Logger {
+	if o := newMgrOptions(opts); o.log != nil {
+		return o.log
+	}
+
+	return newLogger(debug)
+}
+
+//opLog is an operation in progress, which is logged with its context fields when it starts and ends
+type opLog struct {
+	ctx   context.Context
+	log   *slog.Logger
+	start time.Time
+}
+
+//beginOp logs the start of an operation at debug level, attrs are slog key/value pairs which are included in every event
+func beginOp(ctx context.Context, logger *slog.Logger, op string, attrs ...any) *opLog {
+	o := &opLog{
+		ctx:   ctx,
+		log:   logger.With(append([]any{"op", op}, attrs...)...),
+		start: time.Now(),
+	}
+
+	o.log.DebugContext(ctx, "start")
+
+	return o
+}
+
+//add includes further fields which are only known part way through the operation (e.g. a new jobid)
+func (o *opLog) add(attrs ...any) {
+	o.log = o.log.With(attrs...)
+}
+
+//end logs the outcome of the operation with its duration, failures are logged at error level
+//
+//ErrNoDataFound is an empty result rather than a failure, so is logged at debug level.
+func (o *opLog) end(errp *error) {
+	attrs := []any{"duration", time.Since(o.start)}
+
+	if errp == nil || *errp == nil {
+		o.log.DebugContext(o.ctx, "end", attrs...)
+		return
+	}
+
+	attrs = append(attrs, "error", *errp)
+
+	if errors.Is(*errp, ErrNoDataFound) {
+		o.log.DebugContext(o.ctx, "end", attrs...)
+		return
+	}
+
+	o.log.ErrorContext(o.ctx, "failed", attrs...)
+}
@@ -0,0 +1,153 @@
+package dfmgr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func Test_InstanceLoggers(t *testing.T) {
+	ctx := context.Background()
+
+	//two managers with different verbosity
+	var dbg, quiet bytes.Buffer
+
+	newMgr := func(buf *bytes.Buffer, lvl slog.Level) *DfMgr {
+		dfm, err := NewMgrWithOptions(ctx,
+			WithDataflowClient(NewFakeDataflowClient()),
+			WithJobStore(NewMemMgr(ctx)),
+			WithProject("testproject"),
+			WithRegion("europe-west1"),
+			WithLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: lvl}))),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return dfm
+	}
+
+	dfm1 := newMgr(&dbg, slog.LevelDebug)
+	dfm2 := newMgr(&quiet, slog.LevelInfo)
+
+	for _, dfm := range []*DfMgr{dfm1, dfm2} {
+		if _, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !strings.Contains(dbg.String(), "op=JobStart") || !strings.Contains(dbg.String(), "mgr=DfMgr") {
+		t.Fatalf("expected debug events, got %q", dbg.String())
+	}
+
+	if strings.Contains(quiet.String(), "msg=start") || !strings.Contains(quiet.String(), "msg=launched") {
+		t.Fatalf("expected only info events, got %q", quiet.String())
+	}
+}
+func Test_OpLogFields(t *testing.T) {
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+
+	fk := NewFakeDataflowClient()
+
+	dfm, err := NewMgrWithOptions(ctx,
+		WithDataflowClient(fk),
+		WithJobStore(NewMemMgr(ctx)),
+		WithProject("testproject"),
+		WithRegion("europe-west1"),
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fk.FailNext("GetJob", errors.New("api unavailable"))
+
+	if _, err = dfm.GetJobStatus(ctx, meta.JobID); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var events []map[string]interface{}
+
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var ev map[string]interface{}
+		if err = dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+
+		events = append(events, ev)
+	}
+
+	find := func(op, msg string) map[string]interface{} {
+		for _, ev := range events {
+			if ev["op"] == op && ev["msg"] == msg {
+				return ev
+			}
+		}
+
+		t.Fatalf("no %s %s event in %v", op, msg, events)
+		return nil
+	}
+
+	ev := find("JobStart", "end")
+	for k, v := range map[string]interface{}{"appscope": jbappscope, "jobtype": jobtype, "jobid": meta.JobID, "project": "testproject", "region": "europe-west1"} {
+		if ev[k] != v {
+			t.Fatalf("expected %s=%v, got %v", k, v, ev[k])
+		}
+	}
+
+	if _, ok := ev["duration"]; !ok {
+		t.Fatalf("expected a duration, got %v", ev)
+	}
+
+	ev = find("GetJobStatus", "failed")
+	if ev["level"] != "ERROR" || ev["jobid"] != meta.JobID || ev["error"] != "api unavailable" {
+		t.Fatalf("unexpected failure event %v", ev)
+	}
+}
+func Test_InstanceLoggerDefault(t *testing.T) {
+	ctx := context.Background()
+
+	//the default logger is silent unless debug is on
+	if newLogger(false).Enabled(ctx, slog.LevelError) {
+		t.Fatal("expected the default logger to discard events")
+	}
+
+	if !newLogger(true).Enabled(ctx, slog.LevelDebug) {
+		t.Fatal("expected the debug logger to write debug events")
+	}
+
+	//a supplied logger is used in preference to the default
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	if got := optionLogger(true, []Option{WithProject("testproject"), WithLogger(logger)}); got != logger {
+		t.Fatalf("expected the supplied logger, got %v", got)
+	}
+}
+func Test_OpLogNoData(t *testing.T) {
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	//a wrapped ErrNoDataFound is an empty result rather than a failure
+	err := fmt.Errorf("appscope %s: %w", jbappscope, ErrNoDataFound)
+	beginOp(ctx, logger, "GetJobs").end(&err)
+
+	if strings.Contains(buf.String(), "level=ERROR") || !strings.Contains(buf.String(), "msg=end") {
+		t.Fatalf("expected a debug end event, got %q", buf.String())
+	}
+}
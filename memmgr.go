@@ -0,0 +1,369 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemMgr is an in-memory JobStore which mirrors the jobcontrol functions in schema/
+type MemMgr struct {
+	mu      sync.RWMutex
+	seq     int64
+	jobs    map[string]*memJob
+	history []*JobHistory
+	now     func() time.Time
+	window  time.Duration
+}
+
+// memJob is a jobcontrol row
+type memJob struct {
+	id  int64
+	job DsJob
+}
+
+// NewMemMgr returns a new, empty in-memory job store
+func NewMemMgr(ctx context.Context) *MemMgr {
+	return &MemMgr{
+		jobs:   make(map[string]*memJob),
+		now:    time.Now,
+		window: 24 * time.Hour,
+	}
+}
+
+// SaveJob inserts a job, or updates the jobtype and status if the appscope/jobid pair already exists (set_jobcontrol)
+func (mm *MemMgr) SaveJob(ctx context.Context, mdp *DsJob) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	now := mm.now()
+
+	//update the job if it's already present
+	if row, ok := mm.jobs[mdp.JobID]; ok {
+		//jobid is unique across appscopes (uc_jobcontrol_1)
+		if row.job.AppScope != mdp.AppScope {
+			return ErrJobIDConflict
+		}
+
+		changed := row.job.LastStatus != mdp.LastStatus
+
+		row.job.JobType = mdp.JobType
+		row.job.LastStatus = mdp.LastStatus
+		row.job.LastTouched = &now
+
+		if changed {
+			mm.addHistory(&row.job, now, false)
+		}
+
+		return nil
+	}
+
+	//otherwise insert it
+	mm.seq++
+	created, touched := now, now
+	mm.jobs[mdp.JobID] = &memJob{
+		id: mm.seq,
+		job: DsJob{
+			AppScope:    mdp.AppScope,
+			JobID:       mdp.JobID,
+			JobType:     mdp.JobType,
+			LastStatus:  mdp.LastStatus,
+			CreatedDate: &created,
+			LastTouched: &touched,
+		},
+	}
+
+	mm.addHistory(&mm.jobs[mdp.JobID].job, now, false)
+
+	//the first history row records whether the job was imported
+	mm.history[len(mm.history)-1].Imported = mdp.Imported
+
+	//trim the job archive for this appscope
+	mm.deleteArchive(mdp.AppScope, now)
+
+	return nil
+}
+
+// SetJobStatus sets a job status, touching the job only if the status has changed (set_jobstatus)
+func (mm *MemMgr) SetJobStatus(ctx context.Context, jobid string, jobstate JobState) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	row, ok := mm.jobs[jobid]
+	if !ok || row.job.LastStatus == jobstate {
+		return nil
+	}
+
+	now := mm.now()
+	row.job.LastStatus = jobstate
+	row.job.LastTouched = &now
+
+	mm.addHistory(&row.job, now, false)
+
+	return nil
+}
+
+// SetJobStop records a stop request and the job status which followed it (set_jobstop)
+func (mm *MemMgr) SetJobStop(ctx context.Context, jobid string, jobstate JobState, req *StopRequest) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	row, ok := mm.jobs[jobid]
+	if !ok {
+		return nil
+	}
+
+	now := mm.now()
+	stopped := now
+	row.job.LastStatus = jobstate
+	row.job.StopMode = req.Mode
+	row.job.StopReason = req.Reason
+	row.job.StoppedBy = req.RequestedBy
+	row.job.StoppedDate = &stopped
+	row.job.LastTouched = &now
+
+	//stop requests are always recorded
+	mm.addHistory(&row.job, now, true)
+
+	return nil
+}
+
+// GetJob gets a specific job (get_jobcontrol does not return lasttouched)
+func (mm *MemMgr) GetJob(ctx context.Context, jobid string) (*DsJob, error) {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	row, ok := mm.jobs[jobid]
+	if !ok {
+		return nil, ErrNoDataFound
+	}
+
+	jb := copyJob(&row.job)
+	jb.LastTouched = nil
+
+	return jb, nil
+}
+
+// GetJobHistory gets the states recorded for a job, oldest first (get_jobhistory)
+func (mm *MemMgr) GetJobHistory(ctx context.Context, jobid string) ([]*JobHistory, error) {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	result := mm.filterHistory(func(h *JobHistory) bool { return h.JobID == jobid })
+	if len(result) == 0 {
+		return nil, ErrNoDataFound
+	}
+
+	return result, nil
+}
+
+// GetAppScopeJobHistory gets the history of the jobs for an appscope (and jobtype, which can be empty string) which were first recorded within [from, to) (get_appscopejobhistory)
+func (mm *MemMgr) GetAppScopeJobHistory(ctx context.Context, appscope, jobtype string, from, to time.Time) ([]*JobHistory, error) {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	//the history is appended in time order, so the first row seen for a job is its earliest
+	first := make(map[string]bool)
+	for _, h := range mm.history {
+		if h.AppScope != appscope || (jobtype != "" && h.JobType != jobtype) {
+			continue
+		}
+
+		if _, ok := first[h.JobID]; !ok {
+			first[h.JobID] = !h.Observed.Before(from) && h.Observed.Before(to)
+		}
+	}
+
+	result := mm.filterHistory(func(h *JobHistory) bool { return first[h.JobID] })
+	if len(result) == 0 {
+		return nil, ErrNoDataFound
+	}
+
+	return result, nil
+}
+
+// GetAppScopeJobs gets the jobs for a specified appscope, jobtype and jobstate (latter two can be empty string)
+func (mm *MemMgr) GetAppScopeJobs(ctx context.Context, appscope, jobtype string, jobstate JobState) ([]*DsJob, error) {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	rows := mm.filter(func(jb *DsJob) bool {
+		return jb.AppScope == appscope &&
+			(jobtype == "" || jb.JobType == jobtype) &&
+			(jobstate == "" || jb.LastStatus == jobstate)
+	})
+
+	//json_agg over zero rows returns null
+	if len(rows) == 0 {
+		return nil, ErrNoDataFound
+	}
+
+	return copyJobs(rows), nil
+}
+
+// GetLatestAppScopeJob gets up to limit jobs for an appscope and jobtype created in the last 24 hours, newest first
+func (mm *MemMgr) GetLatestAppScopeJob(ctx context.Context, appscope, jobtype string, limit int) ([]*DsJob, error) {
+	if limit < 0 {
+		return nil, ErrNegativeLimit
+	}
+
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	dtlimit := mm.now().Add(-mm.window)
+
+	rows := mm.filter(func(jb *DsJob) bool {
+		return jb.AppScope == appscope &&
+			jb.JobType == jobtype &&
+			!jb.CreatedDate.Before(dtlimit)
+	})
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].CreatedDate.After(*rows[j].CreatedDate)
+	})
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrNoDataFound
+	}
+
+	return copyJobs(rows), nil
+}
+
+// GetAppScopeJobCount gets the count of jobs for a specified appscope, jobtype and jobstate (latter two can be empty string)
+func (mm *MemMgr) GetAppScopeJobCount(ctx context.Context, appscope, jobtype string, jobstate JobState) (int64, error) {
+	jbs, err := mm.GetAppScopeJobs(ctx, appscope, jobtype, jobstate)
+	if err != nil {
+		if errors.Is(err, ErrNoDataFound) {
+			return 0, nil
+		}
+		return -1, err
+	}
+
+	return int64(len(jbs)), nil
+}
+
+// DeleteJob clears a job
+func (mm *MemMgr) DeleteJob(ctx context.Context, appscope, jobid string) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if row, ok := mm.jobs[jobid]; ok && row.job.AppScope == appscope {
+		delete(mm.jobs, jobid)
+	}
+
+	return nil
+}
+
+// DeleteJobArchive clears the job archive (older than 24 hours)
+func (mm *MemMgr) DeleteJobArchive(ctx context.Context, appscope string) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.deleteArchive(appscope, mm.now())
+
+	return nil
+}
+
+// deleteArchive removes the appscope jobs created before the archive window, the caller must hold the write lock
+func (mm *MemMgr) deleteArchive(appscope string, now time.Time) {
+	dtlimit := now.Add(-mm.window)
+
+	for jobid, row := range mm.jobs {
+		if row.job.AppScope == appscope && row.job.CreatedDate.Before(dtlimit) {
+			delete(mm.jobs, jobid)
+		}
+	}
+}
+
+// filter returns the matching jobs in insertion order, the caller must hold the read lock
+func (mm *MemMgr) filter(match func(jb *DsJob) bool) []*DsJob {
+	rows := make([]*memJob, 0, len(mm.jobs))
+	for _, row := range mm.jobs {
+		if match(&row.job) {
+			rows = append(rows, row)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].id < rows[j].id
+	})
+
+	jbs := make([]*DsJob, len(rows))
+	for i, row := range rows {
+		jbs[i] = &row.job
+	}
+
+	return jbs
+}
+
+// addHistory records the job's current state in the history, with its stop request if stop is set, the caller must hold the lock
+func (mm *MemMgr) addHistory(jb *DsJob, now time.Time, stop bool) {
+	h := &JobHistory{
+		AppScope: jb.AppScope,
+		JobID:    jb.JobID,
+		JobType:  jb.JobType,
+		Status:   jb.LastStatus,
+		Observed: &now,
+	}
+
+	if stop {
+		h.StopMode = jb.StopMode
+		h.StopReason = jb.StopReason
+		h.StoppedBy = jb.StoppedBy
+	}
+
+	mm.history = append(mm.history, h)
+}
+
+// filterHistory returns detached copies of the history rows which match, the caller must hold the lock
+func (mm *MemMgr) filterHistory(match func(h *JobHistory) bool) []*JobHistory {
+	var result []*JobHistory
+	for _, h := range mm.history {
+		if match(h) {
+			cp := *h
+			dt := *h.Observed
+			cp.Observed = &dt
+			result = append(result, &cp)
+		}
+	}
+
+	return result
+}
+
+// copyJobs returns detached copies of a set of jobs
+func copyJobs(jbs []*DsJob) []*DsJob {
+	result := make([]*DsJob, len(jbs))
+	for i, jb := range jbs {
+		result[i] = copyJob(jb)
+	}
+
+	return result
+}
+
+// copyJob returns a detached copy of a job
+func copyJob(jb *DsJob) *DsJob {
+	cp := *jb
+
+	if jb.CreatedDate != nil {
+		dt := *jb.CreatedDate
+		cp.CreatedDate = &dt
+	}
+
+	if jb.LastTouched != nil {
+		dt := *jb.LastTouched
+		cp.LastTouched = &dt
+	}
+
+	if jb.StoppedDate != nil {
+		dt := *jb.StoppedDate
+		cp.StoppedDate = &dt
+	}
+
+	return &cp
+}
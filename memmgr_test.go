@@ -0,0 +1,501 @@
+package dfmgr
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+//newTestMemMgr returns a MemMgr with a controllable clock
+func newTestMemMgr(ctx context.Context) (*MemMgr, *time.Time) {
+	now := time.Date(2019, 4, 11, 12, 0, 0, 0, time.UTC)
+	mm := NewMemMgr(ctx)
+	mm.now = func() time.Time { return now }
+
+	return mm, &now
+}
+
+func Test_MemSaveJob(t *testing.T) {
+	ctx := context.Background()
+	mm, now := newTestMemMgr(ctx)
+
+	err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStatePending})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := *now
+	*now = now.Add(time.Minute)
+
+	//saving the same appscope/jobid updates the row
+	err = mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "othertype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jbs, err := mm.GetAppScopeJobs(ctx, appscope, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jbs) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(jbs))
+	}
+
+	if jbs[0].JobType != "othertype" || jbs[0].LastStatus != CnstStateRunning {
+		t.Fatalf("job was not updated: %v", jbs[0])
+	}
+
+	if !jbs[0].CreatedDate.Equal(created) || !jbs[0].LastTouched.Equal(*now) {
+		t.Fatalf("unexpected timestamps: %v %v", jbs[0].CreatedDate, jbs[0].LastTouched)
+	}
+
+	//the jobid is unique across appscopes
+	err = mm.SaveJob(ctx, &DsJob{AppScope: "otherapp", JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != ErrJobIDConflict {
+		t.Fatalf("expected ErrJobIDConflict, got %v", err)
+	}
+}
+func Test_MemSetJobStatus(t *testing.T) {
+	ctx := context.Background()
+	mm, now := newTestMemMgr(ctx)
+
+	err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := *now
+	*now = now.Add(time.Minute)
+
+	//an unchanged status does not touch the job
+	err = mm.SetJobStatus(ctx, "123456", CnstStateRunning)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jbs, err := mm.GetAppScopeJobs(ctx, appscope, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !jbs[0].LastTouched.Equal(saved) {
+		t.Fatalf("expected lasttouched %v, got %v", saved, jbs[0].LastTouched)
+	}
+
+	err = mm.SetJobStatus(ctx, "123456", CnstStateDone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jbs, err = mm.GetAppScopeJobs(ctx, appscope, "", CnstStateDone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !jbs[0].LastTouched.Equal(*now) {
+		t.Fatalf("expected lasttouched %v, got %v", *now, jbs[0].LastTouched)
+	}
+
+	//unknown jobs are ignored
+	err = mm.SetJobStatus(ctx, "unknown", CnstStateDone)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+func Test_MemGetJob(t *testing.T) {
+	ctx := context.Background()
+	mm, _ := newTestMemMgr(ctx)
+
+	_, err := mm.GetJob(ctx, "123456")
+	if err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+
+	err = mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb, err := mm.GetJob(ctx, "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.AppScope != appscope || jb.LastStatus != CnstStateRunning || jb.CreatedDate == nil {
+		t.Fatalf("unexpected job %v", jb)
+	}
+
+	//the returned job is detached from the store
+	jb.LastStatus = CnstStateFailed
+
+	jb, err = mm.GetJob(ctx, "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.LastStatus != CnstStateRunning {
+		t.Fatalf("store was modified through a returned job")
+	}
+}
+func Test_MemGetAppScopeJobs(t *testing.T) {
+	ctx := context.Background()
+	mm, _ := newTestMemMgr(ctx)
+
+	seed := []*DsJob{
+		{AppScope: appscope, JobID: "1", JobType: "typea", LastStatus: CnstStateRunning},
+		{AppScope: appscope, JobID: "2", JobType: "typeb", LastStatus: CnstStateRunning},
+		{AppScope: appscope, JobID: "3", JobType: "typea", LastStatus: CnstStateDone},
+		{AppScope: "otherapp", JobID: "4", JobType: "typea", LastStatus: CnstStateRunning},
+	}
+
+	for _, item := range seed {
+		if err := mm.SaveJob(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		jobtype  string
+		jobstate JobState
+		want     []string
+	}{
+		{"", "", []string{"1", "2", "3"}},
+		{"typea", "", []string{"1", "3"}},
+		{"", CnstStateRunning, []string{"1", "2"}},
+		{"typea", CnstStateDone, []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		jbs, err := mm.GetAppScopeJobs(ctx, appscope, tt.jobtype, tt.jobstate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(jbs) != len(tt.want) {
+			t.Fatalf("%q/%q: expected %v, got %d jobs", tt.jobtype, tt.jobstate, tt.want, len(jbs))
+		}
+
+		for i, item := range jbs {
+			if item.JobID != tt.want[i] {
+				t.Fatalf("%q/%q: expected %v, got %s at %d", tt.jobtype, tt.jobstate, tt.want, item.JobID, i)
+			}
+		}
+
+		ct, err := mm.GetAppScopeJobCount(ctx, appscope, tt.jobtype, tt.jobstate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ct != int64(len(tt.want)) {
+			t.Fatalf("%q/%q: expected count %d, got %d", tt.jobtype, tt.jobstate, len(tt.want), ct)
+		}
+	}
+
+	_, err := mm.GetAppScopeJobs(ctx, appscope, "typec", "")
+	if err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+
+	ct, err := mm.GetAppScopeJobCount(ctx, appscope, "typec", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ct != 0 {
+		t.Fatalf("expected count 0, got %d", ct)
+	}
+}
+func Test_MemGetLatestAppScopeJob(t *testing.T) {
+	ctx := context.Background()
+	mm, now := newTestMemMgr(ctx)
+
+	for _, item := range []string{"1", "2", "3"} {
+		if err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: item, JobType: "typea", LastStatus: CnstStateDone}); err != nil {
+			t.Fatal(err)
+		}
+		*now = now.Add(time.Hour)
+	}
+
+	//job 1 falls outside the 24 hour window
+	*now = now.Add(21*time.Hour + time.Minute)
+
+	jbs, err := mm.GetLatestAppScopeJob(ctx, appscope, "typea", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jbs) != 2 || jbs[0].JobID != "3" || jbs[1].JobID != "2" {
+		t.Fatalf("unexpected jobs %v", jbs)
+	}
+
+	jbs, err = mm.GetLatestAppScopeJob(ctx, appscope, "typea", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jbs) != 1 || jbs[0].JobID != "3" {
+		t.Fatalf("unexpected jobs %v", jbs)
+	}
+
+	//the jobtype is not optional
+	_, err = mm.GetLatestAppScopeJob(ctx, appscope, "", 5)
+	if err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+
+	_, err = mm.GetLatestAppScopeJob(ctx, appscope, "typea", -1)
+	if err != ErrNegativeLimit {
+		t.Fatalf("expected ErrNegativeLimit, got %v", err)
+	}
+}
+func Test_MemDeleteJob(t *testing.T) {
+	ctx := context.Background()
+	mm, _ := newTestMemMgr(ctx)
+
+	err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the appscope must match
+	err = mm.DeleteJob(ctx, "otherapp", "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = mm.GetJob(ctx, "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = mm.DeleteJob(ctx, appscope, "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = mm.GetJob(ctx, "123456"); err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+}
+func Test_MemDeleteJobArchive(t *testing.T) {
+	ctx := context.Background()
+	mm, now := newTestMemMgr(ctx)
+
+	for _, item := range []*DsJob{
+		{AppScope: appscope, JobID: "1", JobType: "typea", LastStatus: CnstStateDone},
+		{AppScope: "otherapp", JobID: "2", JobType: "typea", LastStatus: CnstStateDone},
+	} {
+		if err := mm.SaveJob(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	*now = now.Add(25 * time.Hour)
+
+	err := mm.DeleteJobArchive(ctx, appscope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = mm.GetJob(ctx, "1"); err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+
+	if _, err = mm.GetJob(ctx, "2"); err != nil {
+		t.Fatal(err)
+	}
+
+	//inserting a new job trims the archive for its appscope
+	err = mm.SaveJob(ctx, &DsJob{AppScope: "otherapp", JobID: "3", JobType: "typea", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = mm.GetJob(ctx, "2"); err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+}
+func Test_MemSetJobStop(t *testing.T) {
+	ctx := context.Background()
+	mm, now := newTestMemMgr(ctx)
+
+	err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	*now = now.Add(time.Minute)
+
+	req := &StopRequest{Mode: StopModeDrain, Reason: "backfill complete", RequestedBy: "ops@example.com"}
+
+	err = mm.SetJobStop(ctx, "123456", CnstStateDraining, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jbs, err := mm.GetAppScopeJobs(ctx, appscope, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb := jbs[0]
+	if jb.LastStatus != CnstStateDraining || jb.StopMode != req.Mode || jb.StopReason != req.Reason || jb.StoppedBy != req.RequestedBy {
+		t.Fatalf("unexpected job %v", jb)
+	}
+
+	if !jb.LastTouched.Equal(*now) || jb.StoppedDate == nil || !jb.StoppedDate.Equal(*now) {
+		t.Fatalf("expected lasttouched and stoppeddate %v, got %v", *now, jb)
+	}
+
+	//unknown jobs are ignored
+	err = mm.SetJobStop(ctx, "654321", CnstStateCancelling, &StopRequest{Mode: StopModeCancel})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_MemJobHistory(t *testing.T) {
+	ctx := context.Background()
+	mm, now := newTestMemMgr(ctx)
+
+	err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStatePending})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := *now
+	*now = now.Add(time.Minute)
+
+	//an unchanged status is not recorded
+	for _, state := range []JobState{CnstStatePending, CnstStateRunning, CnstStateRunning} {
+		if err = mm.SetJobStatus(ctx, "123456", state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	*now = now.Add(time.Minute)
+
+	req := &StopRequest{Mode: StopModeCancel, Reason: "superseded", RequestedBy: "ops@example.com"}
+	if err = mm.SetJobStop(ctx, "123456", CnstStateCancelling, req); err != nil {
+		t.Fatal(err)
+	}
+
+	hist, err := mm.GetJobHistory(ctx, "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []JobState{CnstStatePending, CnstStateRunning, CnstStateCancelling}
+	if len(hist) != len(want) {
+		t.Fatalf("expected %v, got %d rows", want, len(hist))
+	}
+
+	for i, h := range hist {
+		if h.Status != want[i] || h.AppScope != appscope || h.JobType != "testerjobtype" {
+			t.Fatalf("expected %s at %d, got %v", want[i], i, h)
+		}
+	}
+
+	if !hist[0].Observed.Equal(created) || !hist[2].Observed.Equal(*now) {
+		t.Fatalf("unexpected timestamps: %v %v", hist[0].Observed, hist[2].Observed)
+	}
+
+	if hist[1].StopMode != "" || hist[2].StopMode != req.Mode || hist[2].StopReason != req.Reason || hist[2].StoppedBy != req.RequestedBy {
+		t.Fatalf("expected only the stop row to record the request, got %v %v", hist[1], hist[2])
+	}
+
+	if _, err = mm.GetJobHistory(ctx, "654321"); err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+}
+
+func Test_MemJobHistoryKept(t *testing.T) {
+	ctx := context.Background()
+	mm, now := newTestMemMgr(ctx)
+
+	for _, jobid := range []string{"123456", "234567"} {
+		err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: jobid, JobType: "testerjobtype", LastStatus: CnstStateRunning})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//as for the sql stores, the history is kept as an audit trail when a job is deleted
+	if err := mm.DeleteJob(ctx, appscope, "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	if hist, err := mm.GetJobHistory(ctx, "123456"); err != nil || len(hist) != 1 {
+		t.Fatalf("expected 1 history row, got %v %v", hist, err)
+	}
+
+	//or archived
+	*now = now.Add(25 * time.Hour)
+
+	if err := mm.DeleteJobArchive(ctx, appscope); err != nil {
+		t.Fatal(err)
+	}
+
+	if hist, err := mm.GetJobHistory(ctx, "234567"); err != nil || len(hist) != 1 {
+		t.Fatalf("expected 1 history row, got %v %v", hist, err)
+	}
+}
+
+func Test_MemAppScopeJobHistory(t *testing.T) {
+	ctx := context.Background()
+	mm, now := newTestMemMgr(ctx)
+	start := *now
+
+	//job 1 is recorded before the window and changes state inside it
+	seed := []*DsJob{
+		{AppScope: appscope, JobID: "1", JobType: "typea", LastStatus: CnstStatePending},
+		{AppScope: appscope, JobID: "2", JobType: "typea", LastStatus: CnstStatePending},
+		{AppScope: appscope, JobID: "3", JobType: "typeb", LastStatus: CnstStatePending},
+		{AppScope: "otherapp", JobID: "4", JobType: "typea", LastStatus: CnstStatePending},
+	}
+
+	for _, item := range seed {
+		if err := mm.SaveJob(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+		*now = now.Add(time.Hour)
+	}
+
+	if err := mm.SetJobStatus(ctx, "2", CnstStateRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mm.SetJobStatus(ctx, "1", CnstStateRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		jobtype string
+		want    []string
+	}{
+		{"", []string{"2", "3", "2"}},
+		{"typea", []string{"2", "2"}},
+	}
+
+	for _, tt := range tests {
+		hist, err := mm.GetAppScopeJobHistory(ctx, appscope, tt.jobtype, start.Add(time.Minute), *now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(hist) != len(tt.want) {
+			t.Fatalf("%q: expected %v, got %d rows", tt.jobtype, tt.want, len(hist))
+		}
+
+		for i, h := range hist {
+			if h.JobID != tt.want[i] {
+				t.Fatalf("%q: expected %v, got %s at %d", tt.jobtype, tt.want, h.JobID, i)
+			}
+		}
+	}
+
+	_, err := mm.GetAppScopeJobHistory(ctx, appscope, "typec", start, *now)
+	if err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package dfmgr
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	google "golang.org/x/oauth2/google"
+	df "google.golang.org/api/dataflow/v1b3"
+	"google.golang.org/api/option"
+)
+
+// Option configures a DfMgr created by NewMgrWithOptions
+type Option func(*mgrOptions)
+
+// mgrOptions holds the dependencies supplied to NewMgrWithOptions
+type mgrOptions struct {
+	httpClient *http.Client
+	dfsvc      *df.Service
+	dfc        DataflowClient
+	ds         JobStore
+	jd         JobDefinitionSource
+	project    string
+	region     string
+	log        *slog.Logger
+}
+
+// WithHTTPClient builds the dataflow service from an existing (authorised) http client
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *mgrOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithDataflowService uses an existing dataflow service
+func WithDataflowService(svc *df.Service) Option {
+	return func(o *mgrOptions) {
+		o.dfsvc = svc
+	}
+}
+
+// WithDataflowClient uses an existing DataflowClient (e.g. FakeDataflowClient)
+func WithDataflowClient(dfc DataflowClient) Option {
+	return func(o *mgrOptions) {
+		o.dfc = dfc
+	}
+}
+
+// WithJobStore tracks jobs in the supplied job store
+func WithJobStore(ds JobStore) Option {
+	return func(o *mgrOptions) {
+		o.ds = ds
+	}
+}
+
+// WithJobDefinitionSource reads and writes job definitions through the supplied source
+func WithJobDefinitionSource(jd JobDefinitionSource) Option {
+	return func(o *mgrOptions) {
+		o.jd = jd
+	}
+}
+
+// WithProject sets the GCP project which jobs run in
+func WithProject(project string) Option {
+	return func(o *mgrOptions) {
+		o.project = project
+	}
+}
+
+// WithRegion sets the GCP region which jobs run in
+func WithRegion(region string) Option {
+	return func(o *mgrOptions) {
+		o.region = region
+	}
+}
+
+// WithLogger logs through the supplied logger, debug events (operation start/end) are written if its handler enables them
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *mgrOptions) {
+		o.log = logger
+	}
+}
+
+// NewMgrWithOptions returns a new manager built from the supplied dependencies, without reading any environment variables
+//
+// A project, region and job store are required. The dataflow client is taken from WithDataflowClient, WithDataflowService
+// or WithHTTPClient (in that order), falling back to the application default credentials. Without WithLogger, the manager
+// is silent.
+func NewMgrWithOptions(ctx context.Context, opts ...Option) (*DfMgr, error) {
+	o := newMgrOptions(opts)
+
+	if o.project == "" {
+		return nil, ErrNoProject
+	}
+
+	if o.region == "" {
+		return nil, ErrNoRegion
+	}
+
+	if o.ds == nil {
+		return nil, ErrNoJobStore
+	}
+
+	dfc := o.dfc
+	if dfc == nil {
+		dfs := o.dfsvc
+
+		if dfs == nil {
+			client := o.httpClient
+
+			//fall back to the default service account
+			if client == nil {
+				var err error
+				client, err = google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			var err error
+			dfs, err = df.NewService(ctx, option.WithHTTPClient(client))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		dfc = NewDataflowClient(dfs)
+	}
+
+	logger := o.log
+	if logger == nil {
+		logger = newLogger(false)
+	}
+
+	abm := &DfMgr{
+		dfc:     dfc,
+		ds:      o.ds,
+		jd:      o.jd,
+		project: o.project,
+		region:  o.region,
+		log:     logger.With("mgr", "DfMgr", "project", o.project, "region", o.region),
+	}
+
+	return abm, nil
+}
+
+// newMgrOptions applies the options in order
+func newMgrOptions(opts []Option) *mgrOptions {
+	o := &mgrOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
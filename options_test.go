@@ -0,0 +1,122 @@
+package dfmgr
+
+import (
+	"context"
+	"testing"
+
+	cfg "github.com/lidstromberg/config"
+)
+
+func Test_NewMgrWithOptions(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		opts []Option
+		want error
+	}{
+		{[]Option{WithRegion("europe-west1"), WithJobStore(NewMemMgr(ctx))}, ErrNoProject},
+		{[]Option{WithProject("testproject"), WithJobStore(NewMemMgr(ctx))}, ErrNoRegion},
+		{[]Option{WithProject("testproject"), WithRegion("europe-west1")}, ErrNoJobStore},
+	}
+
+	for _, tt := range tests {
+		_, err := NewMgrWithOptions(ctx, append(tt.opts, WithDataflowClient(NewFakeDataflowClient()))...)
+		if err != tt.want {
+			t.Fatalf("expected %v, got %v", tt.want, err)
+		}
+	}
+
+	dfm, err := NewMgrWithOptions(ctx,
+		WithDataflowClient(NewFakeDataflowClient()),
+		WithJobStore(NewMemMgr(ctx)),
+		WithProject("testproject"),
+		WithRegion("europe-west1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//no job definition source was supplied
+	_, err = dfm.GetGcsJobDefinition(ctx, "dataflowjobdef.json")
+	if err != ErrNoJobDefinitionSource {
+		t.Fatalf("expected ErrNoJobDefinitionSource, got %v", err)
+	}
+}
+func Test_DirJobDefinitionSource(t *testing.T) {
+	ctx := context.Background()
+
+	dfm, err := NewMgrWithOptions(ctx,
+		WithDataflowClient(NewFakeDataflowClient()),
+		WithJobStore(NewMemMgr(ctx)),
+		WithJobDefinitionSource(NewDirJobDefinitionSource("jobdef")),
+		WithProject("testproject"),
+		WithRegion("europe-west1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	param, err := dfm.GetGcsJobDefinition(ctx, "dataflowjobdef.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if param.RuntimeEnvironment["maxWorkers"] != "1" || param.JobRequest["jobName"] != "dflauncher%s" {
+		t.Fatalf("unexpected job definition %v", param)
+	}
+
+	//round trip the definition through a scratch directory
+	src := NewDirJobDefinitionSource(t.TempDir())
+
+	err = src.SetJobDefinition(ctx, "copy/dataflowjobdef.json", param)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := src.GetJobDefinition(ctx, "copy/dataflowjobdef.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cp.JobRequest["gcsPath"] != param.JobRequest["gcsPath"] {
+		t.Fatalf("expected %s, got %s", param.JobRequest["gcsPath"], cp.JobRequest["gcsPath"])
+	}
+}
+func Test_NewStoreMgr(t *testing.T) {
+	ctx := context.Background()
+
+	//the sql settings are only required by the postgres job store
+	setTestEnv(t, map[string]string{
+		"DF_SQLDST": "",
+		"DF_SQLCNX": "",
+	})
+
+	fc := NewFakeDataflowClient()
+	mm := NewMemMgr(ctx)
+
+	dfm, err := NewStoreMgr(ctx, cfg.NewConfig(ctx), mm,
+		WithDataflowClient(fc),
+		WithJobDefinitionSource(NewDirJobDefinitionSource("jobdef")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	param, err := dfm.GetGcsJobDefinition(ctx, "dataflowjobdef.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, param)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = mm.GetJob(ctx, meta.JobID); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fc.Launched()) != 1 {
+		t.Fatalf("expected 1 launch, got %d", len(fc.Launched()))
+	}
+}
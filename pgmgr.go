@@ -2,31 +2,57 @@ package dfmgr
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
 
 	cfg "github.com/lidstromberg/config"
-	lg "github.com/lidstromberg/log"
 
 	"golang.org/x/net/context"
 
 	"database/sql"
 
-	//blank import required for db
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+//pgUniqueViolation is the postgres error code for a unique constraint failure
+const pgUniqueViolation = "23505"
+
 //PgMgr handles interactions with a postgres db store
 type PgMgr struct {
-	ds *sql.DB
+	ds  *sql.DB
+	log *slog.Logger
 }
 
-//NewPgMgr creates a new manager
+//NewPgMgr creates a new manager, which is silent unless EnvDebugOn is set
 func NewPgMgr(ctx context.Context, bc cfg.ConfigSetting) (*PgMgr, error) {
-	preflight(ctx, bc)
+	debug, err := preflight(ctx, bc, true)
+	if err != nil {
+		return nil, err
+	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "NewPgMgr", "info", "start")
+	return newPgMgr(ctx, bc, newLogger(debug))
+}
+
+//NewPgMgrWithLogger creates a new manager which logs to logger (or as NewPgMgr, if nil)
+func NewPgMgrWithLogger(ctx context.Context, bc cfg.ConfigSetting, logger *slog.Logger) (*PgMgr, error) {
+	debug, err := preflight(ctx, bc, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if logger == nil {
+		logger = newLogger(debug)
 	}
 
+	return newPgMgr(ctx, bc, logger)
+}
+
+//newPgMgr creates a new manager from settings which preflight has already loaded
+func newPgMgr(ctx context.Context, bc cfg.ConfigSetting, logger *slog.Logger) (*PgMgr, error) {
+	logger = logger.With("mgr", "PgMgr")
+	logger.DebugContext(ctx, "start", "op", "NewPgMgr")
+
 	db, err := sql.Open(bc.GetConfigValue(ctx, "EnvSqlDst"), bc.GetConfigValue(ctx, "EnvSqlConnection"))
 	if err != nil {
 		return nil, err
@@ -37,64 +63,87 @@ func NewPgMgr(ctx context.Context, bc cfg.ConfigSetting) (*PgMgr, error) {
 	}
 
 	pg1 := &PgMgr{
-		ds: db,
+		ds:  db,
+		log: logger,
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "NewPgMgr", "info", "end")
-	}
+	logger.DebugContext(ctx, "end", "op", "NewPgMgr")
 
 	return pg1, nil
 }
 
-//SaveJob saves a job
-func (pgm *PgMgr) SaveJob(ctx context.Context, mdp *DsJob) error {
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "SaveJob", "info", "start")
+//NewPgMgrFromDB creates a new manager which uses an existing db pool, logging to logger (or discarding events, if nil)
+func NewPgMgrFromDB(db *sql.DB, logger *slog.Logger) *PgMgr {
+	if logger == nil {
+		logger = newLogger(false)
+	}
+
+	return &PgMgr{
+		ds:  db,
+		log: logger.With("mgr", "PgMgr"),
 	}
+}
+
+//Locker returns a Locker for the postgres advisory lock key, so that replicas sharing the db elect a single reconciler
+func (pgm *PgMgr) Locker(key int64) *PgLocker {
+	return NewPgLocker(pgm.ds, key)
+}
+
+//SaveJob saves a job
+func (pgm *PgMgr) SaveJob(ctx context.Context, mdp *DsJob) (err error) {
+	op := beginOp(ctx, pgm.log, "SaveJob", "appscope", mdp.AppScope, "jobid", mdp.JobID, "jobtype", mdp.JobType, "jobstate", mdp.LastStatus)
+	defer op.end(&err)
 
 	//run the query
-	_, err := pgm.ds.Exec("select public.set_jobcontrol($1, $2, $3, $4)", mdp.AppScope, mdp.JobID, mdp.JobType, mdp.LastStatus)
+	_, err = pgm.ds.Exec("select public.set_jobcontrol($1, $2, $3, $4, $5)", mdp.AppScope, mdp.JobID, mdp.JobType, string(mdp.LastStatus), mdp.Imported)
+	if isPgUniqueViolation(err) {
+		//the jobid is already registered to another appscope
+		return ErrJobIDConflict
+	}
 	if err != nil {
 		return err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "SaveJob", "info", "end")
-	}
-
 	return nil
 }
 
 //SetJobStatus sets a job status
-func (pgm *PgMgr) SetJobStatus(ctx context.Context, jobid, jobstate string) error {
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "SetJobStatus", "info", "start")
-	}
+func (pgm *PgMgr) SetJobStatus(ctx context.Context, jobid string, jobstate JobState) (err error) {
+	op := beginOp(ctx, pgm.log, "SetJobStatus", "jobid", jobid, "jobstate", jobstate)
+	defer op.end(&err)
 
-	_, err := pgm.ds.Exec("select public.set_jobstatus($1,$2)", jobid, jobstate)
+	_, err = pgm.ds.Exec("select public.set_jobstatus($1,$2)", jobid, string(jobstate))
 	if err != nil {
 		return err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "SetJobStatus", "info", "end")
+	return nil
+}
+
+//SetJobStop records a stop request and the job status which followed it
+func (pgm *PgMgr) SetJobStop(ctx context.Context, jobid string, jobstate JobState, req *StopRequest) (err error) {
+	op := beginOp(ctx, pgm.log, "SetJobStop", "jobid", jobid, "jobstate", jobstate, "stopmode", req.Mode, "stoppedby", req.RequestedBy)
+	defer op.end(&err)
+
+	_, err = pgm.ds.Exec("select public.set_jobstop($1,$2,$3,$4,$5)", jobid, string(jobstate), string(req.Mode), req.Reason, req.RequestedBy)
+	if err != nil {
+		return err
 	}
+
 	return nil
 }
 
 //GetJob gets a specific job
-func (pgm *PgMgr) GetJob(ctx context.Context, jobid string) (*DsJob, error) {
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "GetJob", "info", "start")
-	}
+func (pgm *PgMgr) GetJob(ctx context.Context, jobid string) (_ *DsJob, err error) {
+	op := beginOp(ctx, pgm.log, "GetJob", "jobid", jobid)
+	defer op.end(&err)
 
 	//run the query
 	var (
 		jsonString sql.NullString
 		param      DsJob
 	)
-	err := pgm.ds.QueryRow("select get_jobcontrol as rs from public.get_jobcontrol($1)", jobid).Scan(&jsonString)
+	err = pgm.ds.QueryRow("select get_jobcontrol as rs from public.get_jobcontrol($1)", jobid).Scan(&jsonString)
 	if err != nil {
 		return nil, err
 	}
@@ -110,27 +159,53 @@ func (pgm *PgMgr) GetJob(ctx context.Context, jobid string) (*DsJob, error) {
 		return nil, err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "GetJob", "info", "end")
-	}
-
 	//return the model parameter string
 	return &param, nil
 }
 
-//GetAppScopeJobs gets the Jobs for a specified appscope, jobtype and jobstate (latter two can be empty string)
-func (pgm *PgMgr) GetAppScopeJobs(ctx context.Context, appscope, jobtype, jobstate string) ([]*DsJob, error) {
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "GetAppScopeJobs", "info", "start")
+//GetJobHistory gets the states recorded for a job, oldest first
+func (pgm *PgMgr) GetJobHistory(ctx context.Context, jobid string) (_ []*JobHistory, err error) {
+	op := beginOp(ctx, pgm.log, "GetJobHistory", "jobid", jobid)
+	defer op.end(&err)
+
+	//run the query
+	var (
+		jsonString sql.NullString
+		param      []*JobHistory
+	)
+
+	err = pgm.ds.QueryRow("select get_jobhistory as rs from public.get_jobhistory($1)", jobid).Scan(&jsonString)
+	if err != nil {
+		return nil, err
+	}
+
+	//if the result is null, return the appropriate message
+	if !jsonString.Valid {
+		return nil, ErrNoDataFound
 	}
 
+	//convert the json result
+	err = json.Unmarshal([]byte(jsonString.String), &param)
+	if err != nil {
+		return nil, err
+	}
+
+	//return the history
+	return param, nil
+}
+
+//GetAppScopeJobHistory gets the history of the jobs for an appscope (and jobtype, which can be empty string) which were first recorded within [from, to), oldest first
+func (pgm *PgMgr) GetAppScopeJobHistory(ctx context.Context, appscope, jobtype string, from, to time.Time) (_ []*JobHistory, err error) {
+	op := beginOp(ctx, pgm.log, "GetAppScopeJobHistory", "appscope", appscope, "jobtype", jobtype, "from", from, "to", to)
+	defer op.end(&err)
+
 	//run the query
 	var (
 		jsonString sql.NullString
-		param      []*DsJob
+		param      []*JobHistory
 	)
 
-	err := pgm.ds.QueryRow("select get_appscopejobcontrol as rs from public.get_appscopejobcontrol($1, $2, $3)", appscope, jobtype, jobstate).Scan(&jsonString)
+	err = pgm.ds.QueryRow("select get_appscopejobhistory as rs from public.get_appscopejobhistory($1, $2, $3, $4)", appscope, jobtype, from, to).Scan(&jsonString)
 	if err != nil {
 		return nil, err
 	}
@@ -146,8 +221,35 @@ func (pgm *PgMgr) GetAppScopeJobs(ctx context.Context, appscope, jobtype, jobsta
 		return nil, err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "GetAppScopeJobs", "info", "end")
+	//return the history
+	return param, nil
+}
+
+//GetAppScopeJobs gets the Jobs for a specified appscope, jobtype and jobstate (latter two can be empty string)
+func (pgm *PgMgr) GetAppScopeJobs(ctx context.Context, appscope, jobtype string, jobstate JobState) (_ []*DsJob, err error) {
+	op := beginOp(ctx, pgm.log, "GetAppScopeJobs", "appscope", appscope, "jobtype", jobtype, "jobstate", jobstate)
+	defer op.end(&err)
+
+	//run the query
+	var (
+		jsonString sql.NullString
+		param      []*DsJob
+	)
+
+	err = pgm.ds.QueryRow("select get_appscopejobcontrol as rs from public.get_appscopejobcontrol($1, $2, $3)", appscope, jobtype, string(jobstate)).Scan(&jsonString)
+	if err != nil {
+		return nil, err
+	}
+
+	//if the result is null, return the appropriate message
+	if !jsonString.Valid {
+		return nil, ErrNoDataFound
+	}
+
+	//convert the json result
+	err = json.Unmarshal([]byte(jsonString.String), &param)
+	if err != nil {
+		return nil, err
 	}
 
 	//return the model parameter string
@@ -155,10 +257,9 @@ func (pgm *PgMgr) GetAppScopeJobs(ctx context.Context, appscope, jobtype, jobsta
 }
 
 //GetLatestAppScopeJob gets the lastest Job for a specified appscope
-func (pgm *PgMgr) GetLatestAppScopeJob(ctx context.Context, appscope, jobtype string, limit int) ([]*DsJob, error) {
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "GetLatestAppScopeJob", "info", "start")
-	}
+func (pgm *PgMgr) GetLatestAppScopeJob(ctx context.Context, appscope, jobtype string, limit int) (_ []*DsJob, err error) {
+	op := beginOp(ctx, pgm.log, "GetLatestAppScopeJob", "appscope", appscope, "jobtype", jobtype, "limit", limit)
+	defer op.end(&err)
 
 	//run the query
 	var (
@@ -166,7 +267,7 @@ func (pgm *PgMgr) GetLatestAppScopeJob(ctx context.Context, appscope, jobtype st
 		param      []*DsJob
 	)
 
-	err := pgm.ds.QueryRow("select get_latestappscopejobcontrol as rs from public.get_latestappscopejobcontrol($1, $2, $3)", appscope, jobtype, limit).Scan(&jsonString)
+	err = pgm.ds.QueryRow("select get_latestappscopejobcontrol as rs from public.get_latestappscopejobcontrol($1, $2, $3)", appscope, jobtype, limit).Scan(&jsonString)
 	if err != nil {
 		return nil, err
 	}
@@ -182,23 +283,18 @@ func (pgm *PgMgr) GetLatestAppScopeJob(ctx context.Context, appscope, jobtype st
 		return nil, err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "GetLatestAppScopeJob", "info", "end")
-	}
-
 	//return the model parameter string
 	return param, nil
 }
 
 //GetAppScopeJobCount gets the count of Jobs for a specified appscope
-func (pgm *PgMgr) GetAppScopeJobCount(ctx context.Context, appscope, jobtype, jobstate string) (int64, error) {
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "GetAppScopeJobCount", "info", "start")
-	}
+func (pgm *PgMgr) GetAppScopeJobCount(ctx context.Context, appscope, jobtype string, jobstate JobState) (_ int64, err error) {
+	op := beginOp(ctx, pgm.log, "GetAppScopeJobCount", "appscope", appscope, "jobtype", jobtype, "jobstate", jobstate)
+	defer op.end(&err)
 
 	//run the query
 	var result sql.NullInt64
-	err := pgm.ds.QueryRow("select jsonb_array_length(get_appscopejobcontrol) as rs from public.get_appscopejobcontrol($1, $2, $3)", appscope, jobtype, jobstate).Scan(&result)
+	err = pgm.ds.QueryRow("select jsonb_array_length(get_appscopejobcontrol) as rs from public.get_appscopejobcontrol($1, $2, $3)", appscope, jobtype, string(jobstate)).Scan(&result)
 	if err != nil {
 		return -1, err
 	}
@@ -208,48 +304,40 @@ func (pgm *PgMgr) GetAppScopeJobCount(ctx context.Context, appscope, jobtype, jo
 		return 0, nil
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "GetAppScopeJobCount", "info", "end")
-	}
-
 	//return the result
 	return result.Int64, nil
 }
 
 //DeleteJob clears a job
-func (pgm *PgMgr) DeleteJob(ctx context.Context, appscope, jobid string) error {
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "DeleteJob", "info", "start")
-	}
+func (pgm *PgMgr) DeleteJob(ctx context.Context, appscope, jobid string) (err error) {
+	op := beginOp(ctx, pgm.log, "DeleteJob", "appscope", appscope, "jobid", jobid)
+	defer op.end(&err)
 
 	//run the query
-	_, err := pgm.ds.Exec("select public.delete_jobcontrol($1, $2)", appscope, jobid)
+	_, err = pgm.ds.Exec("select public.delete_jobcontrol($1, $2)", appscope, jobid)
 	if err != nil {
 		return err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "DeleteJob", "info", "end")
-	}
-
 	return nil
 }
 
 //DeleteJobArchive clears the job archive (older than 24 hours)
-func (pgm *PgMgr) DeleteJobArchive(ctx context.Context, appscope string) error {
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "DeleteJobArchive", "info", "start")
-	}
+func (pgm *PgMgr) DeleteJobArchive(ctx context.Context, appscope string) (err error) {
+	op := beginOp(ctx, pgm.log, "DeleteJobArchive", "appscope", appscope)
+	defer op.end(&err)
 
 	//run the query
-	_, err := pgm.ds.Exec("select public.delete_jobcontrolarchive($1)", appscope)
+	_, err = pgm.ds.Exec("select public.delete_jobcontrolarchive($1)", appscope)
 	if err != nil {
 		return err
 	}
 
-	if EnvDebugOn {
-		lg.LogEvent("PgMgr", "DeleteJobArchive", "info", "end")
-	}
-
 	return nil
 }
+
+//isPgUniqueViolation reports whether err is a unique constraint failure
+func isPgUniqueViolation(err error) bool {
+	var pe *pq.Error
+	return errors.As(err, &pe) && pe.Code == pgUniqueViolation
+}
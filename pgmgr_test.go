@@ -46,6 +46,26 @@ func Test_SaveJob(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+func Test_SaveJobConflict(t *testing.T) {
+	ctx := context.Background()
+	bc := cfg.NewConfig(ctx)
+
+	ab, err := NewPgMgr(ctx, bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ab.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123457", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the jobid is unique across appscopes
+	err = ab.SaveJob(ctx, &DsJob{AppScope: "otherapp", JobID: "123457", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != ErrJobIDConflict {
+		t.Fatalf("expected ErrJobIDConflict, got %v", err)
+	}
+}
 func Test_GetJob(t *testing.T) {
 	ctx := context.Background()
 	bc := cfg.NewConfig(ctx)
@@ -64,6 +84,88 @@ func Test_GetJob(t *testing.T) {
 
 	t.Logf("Job is %v", jb)
 }
+func Test_SetJobStop(t *testing.T) {
+	ctx := context.Background()
+	bc := cfg.NewConfig(ctx)
+
+	ab, err := NewPgMgr(ctx, bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	JobID := "123456"
+
+	req := &StopRequest{Mode: StopModeDrain, Reason: "backfill complete", RequestedBy: "ops@example.com"}
+
+	err = ab.SetJobStop(ctx, JobID, CnstStateDraining, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb, err := ab.GetJob(ctx, JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.LastStatus != CnstStateDraining || jb.StopMode != req.Mode || jb.StopReason != req.Reason || jb.StoppedBy != req.RequestedBy || jb.StoppedDate == nil {
+		t.Fatalf("unexpected job %v", jb)
+	}
+}
+func Test_GetJobHistory(t *testing.T) {
+	ctx := context.Background()
+	bc := cfg.NewConfig(ctx)
+
+	ab, err := NewPgMgr(ctx, bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	JobID := "123456"
+
+	hist, err := ab.GetJobHistory(ctx, JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, h := range hist {
+		t.Logf("%s %s %s", h.Observed, h.Status, h.StopMode)
+	}
+}
+func Test_PgLocker(t *testing.T) {
+	ctx := context.Background()
+	bc := cfg.NewConfig(ctx)
+
+	ab, err := NewPgMgr(ctx, bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := ab.Locker(20261018), ab.Locker(20261018)
+
+	ok, err := a.TryLock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("expected the lock, got %v %v", ok, err)
+	}
+
+	//a second session cannot take the lock until it is released
+	ok, err = b.TryLock(ctx)
+	if err != nil || ok {
+		t.Fatalf("expected the lock to be held, got %v %v", ok, err)
+	}
+
+	if err = a.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = b.TryLock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("expected the lock, got %v %v", ok, err)
+	}
+
+	if err = b.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
 func Test_GetJobCount1(t *testing.T) {
 	ctx := context.Background()
 	bc := cfg.NewConfig(ctx)
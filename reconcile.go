@@ -0,0 +1,120 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"google.golang.org/api/googleapi"
+)
+
+// ReconcileOptions controls a reconciliation sweep, zero values use the defaults
+type ReconcileOptions struct {
+	//JobType limits the sweep to a single jobtype (default all jobtypes)
+	JobType string
+	//Concurrency is the maximum number of jobs fetched from dataflow at once (default 4)
+	Concurrency int
+}
+
+// reconcile option defaults
+const defaultReconcileConcurrency = 4
+
+// ReconcileReport is the outcome of a reconciliation sweep
+type ReconcileReport struct {
+	AppScope string
+	//Checked is the number of active jobs which were fetched from dataflow
+	Checked int
+	//Changed are the jobs whose recorded status was updated
+	Changed []JobEvent
+	//Missing are the jobs which no longer exist in dataflow, their recorded status is left unchanged
+	Missing []*DsJob
+	//Failed are the jobs which could not be fetched or recorded, with the error
+	Failed []JobEvent
+}
+
+// Reconcile refreshes the recorded status of every job in an appscope which has not reached a terminal state, so that
+// jobs nobody has polled do not go stale. Jobs are fetched from dataflow concurrently (see ReconcileOptions), and a job
+// which cannot be fetched does not stop the sweep, it is reported instead. ro may be nil to use the defaults.
+func (dfm *DfMgr) Reconcile(ctx context.Context, appscope string, ro *ReconcileOptions) (_ *ReconcileReport, err error) {
+	var o ReconcileOptions
+	if ro != nil {
+		o = *ro
+	}
+
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultReconcileConcurrency
+	}
+
+	op := beginOp(ctx, dfm.log, "Reconcile", "appscope", appscope, "jobtype", o.JobType, "concurrency", o.Concurrency)
+	defer op.end(&err)
+
+	report := &ReconcileReport{AppScope: appscope}
+
+	jbs, err := dfm.ds.GetAppScopeJobs(ctx, appscope, o.JobType, "")
+	if errors.Is(err, ErrNoDataFound) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var active []*DsJob
+	for _, jb := range jbs {
+		if jb.LastStatus.IsActive() {
+			active = append(active, jb)
+		}
+	}
+
+	//results are held in job order, so that the report does not depend on which fetch finished first
+	events := make([]JobEvent, len(active))
+	changed := make([]bool, len(active))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, o.Concurrency)
+
+	for i, jb := range active {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			events[i], changed[i] = dfm.pollWatchedJob(ctx, &watchedJob{id: jb.JobID, state: jb.LastStatus})
+		}()
+	}
+
+	wg.Wait()
+
+	for i, ev := range events {
+		//the event carries the job unless the fetch itself failed
+		if ev.Err == nil || ev.Job != nil {
+			report.Checked++
+		}
+
+		switch {
+		case isNotFound(ev.Err):
+			report.Missing = append(report.Missing, active[i])
+		case ev.Err != nil:
+			report.Failed = append(report.Failed, ev)
+		case changed[i]:
+			report.Changed = append(report.Changed, ev)
+		}
+	}
+
+	op.add("checked", report.Checked, "changed", len(report.Changed), "missing", len(report.Missing), "failed", len(report.Failed))
+
+	return report, nil
+}
+
+// isNotFound reports whether an error is dataflow's response for a job which does not exist
+func isNotFound(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusNotFound
+}
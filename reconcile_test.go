@@ -0,0 +1,86 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, mm := newFakeMgr(ctx, t)
+
+	//nothing to reconcile
+	report, err := dfm.Reconcile(ctx, jbappscope, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Checked != 0 {
+		t.Fatalf("expected no jobs to be checked, got %d", report.Checked)
+	}
+
+	var ids []string
+
+	for _, script := range [][]JobState{
+		{CnstStateRunning, CnstStateDone},
+		{CnstStatePending, CnstStatePending},
+		{CnstStateFailed},
+	} {
+		fc.Script(script...)
+
+		meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, meta.JobID)
+	}
+
+	//a job which was recorded but no longer exists in dataflow
+	err = mm.SaveJob(ctx, &DsJob{AppScope: jbappscope, JobID: "missing", JobType: jobtype, LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = dfm.Reconcile(ctx, jbappscope, &ReconcileOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the failed job is terminal and is not checked, and the missing job could not be fetched
+	if report.Checked != 2 || len(report.Failed) != 0 {
+		t.Fatalf("unexpected report %v", report)
+	}
+
+	if len(report.Changed) != 1 || report.Changed[0].JobID != ids[0] || report.Changed[0].From != CnstStateRunning || report.Changed[0].To != CnstStateDone {
+		t.Fatalf("expected %s to change to %s, got %v", ids[0], CnstStateDone, report.Changed)
+	}
+
+	if len(report.Missing) != 1 || report.Missing[0].JobID != "missing" {
+		t.Fatalf("expected the missing job to be reported, got %v", report.Missing)
+	}
+
+	jb, err := mm.GetJob(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.LastStatus != CnstStateDone {
+		t.Fatalf("expected %s, got %s", CnstStateDone, jb.LastStatus)
+	}
+
+	//api errors are reported without stopping the sweep
+	errAPI := errors.New("api unavailable")
+	fc.FailNext("GetJob", errAPI)
+
+	report, err = dfm.Reconcile(ctx, jbappscope, &ReconcileOptions{JobType: jobtype, Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//neither the pending job nor the missing job could be fetched
+	if report.Checked != 0 || len(report.Failed) != 1 || report.Failed[0].Err != errAPI {
+		t.Fatalf("expected one failed job, got %v", report)
+	}
+}
@@ -0,0 +1,97 @@
+package dfmgr
+
+import (
+	"context"
+	"time"
+)
+
+// ReconcilerOptions controls a reconciler started by RunReconciler, zero values use the defaults
+type ReconcilerOptions struct {
+	//AppScopes are the appscopes which are reconciled on each sweep (required)
+	AppScopes []string
+	//Interval is the delay between sweeps, and between attempts to take the lock (default 1m)
+	Interval time.Duration
+	//Reconcile controls each appscope's sweep (see ReconcileOptions)
+	Reconcile *ReconcileOptions
+	//OnReport is called with the report of each appscope's sweep, if set
+	OnReport func(*ReconcileReport)
+}
+
+// reconciler option defaults
+const defaultReconcilerInterval = time.Minute
+
+// RunReconciler periodically reconciles the active jobs of a set of appscopes (see Reconcile) until the context ends, which
+// it returns. Any number of replicas can run a reconciler, only the one holding the lock sweeps, and the others take over
+// if it stops or loses the lock. Sweep errors are logged and do not stop the reconciler.
+func (dfm *DfMgr) RunReconciler(ctx context.Context, lk Locker, ro *ReconcilerOptions) error {
+	var o ReconcilerOptions
+	if ro != nil {
+		o = *ro
+	}
+
+	if len(o.AppScopes) == 0 {
+		return ErrNoAppScopes
+	}
+
+	if lk == nil {
+		return ErrNoLocker
+	}
+
+	if o.Interval <= 0 {
+		o.Interval = defaultReconcilerInterval
+	}
+
+	log := dfm.log.With("op", "RunReconciler")
+
+	var leader bool
+
+	//release the lock on the way out, even though the context has ended
+	defer func() {
+		if leader {
+			if err := lk.Unlock(context.WithoutCancel(ctx)); err != nil {
+				log.ErrorContext(ctx, "unlock failed", "error", err)
+			}
+		}
+	}()
+
+	for {
+		held, err := lk.TryLock(ctx)
+		if err != nil {
+			log.ErrorContext(ctx, "lock failed", "error", err)
+		}
+
+		if held != leader {
+			log.InfoContext(ctx, "leadership changed", "leader", held)
+			leader = held
+		}
+
+		if leader {
+			dfm.reconcileAppScopes(ctx, o)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(o.Interval):
+		}
+	}
+}
+
+// reconcileAppScopes runs a single sweep of each appscope
+func (dfm *DfMgr) reconcileAppScopes(ctx context.Context, o ReconcilerOptions) {
+	for _, appscope := range o.AppScopes {
+		if ctx.Err() != nil {
+			return
+		}
+
+		report, err := dfm.Reconcile(ctx, appscope, o.Reconcile)
+		if err != nil {
+			//Reconcile logs its own failure
+			continue
+		}
+
+		if o.OnReport != nil {
+			o.OnReport(report)
+		}
+	}
+}
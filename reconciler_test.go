@@ -0,0 +1,94 @@
+package dfmgr
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_RunReconciler(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, _ := newFakeMgr(ctx, t)
+	fc.Script(CnstStateRunning)
+
+	if _, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam()); err != nil {
+		t.Fatal(err)
+	}
+
+	ll := NewLocalLock()
+
+	if err := dfm.RunReconciler(ctx, ll.Locker(), nil); err != ErrNoAppScopes {
+		t.Fatalf("expected %v, got %v", ErrNoAppScopes, err)
+	}
+
+	if err := dfm.RunReconciler(ctx, nil, &ReconcilerOptions{AppScopes: []string{jbappscope}}); err != ErrNoLocker {
+		t.Fatalf("expected %v, got %v", ErrNoLocker, err)
+	}
+
+	//start a replica, which sends each of its reports on its own channel
+	start := func(ctx context.Context) (<-chan *ReconcileReport, <-chan error) {
+		reports := make(chan *ReconcileReport, 100)
+		done := make(chan error, 1)
+
+		ro := &ReconcilerOptions{
+			AppScopes: []string{jbappscope},
+			Interval:  time.Millisecond,
+			OnReport: func(r *ReconcileReport) {
+				select {
+				case reports <- r:
+				default:
+				}
+			},
+		}
+
+		go func() { done <- dfm.RunReconciler(ctx, ll.Locker(), ro) }()
+
+		return reports, done
+	}
+
+	actx, acancel := context.WithCancel(ctx)
+	defer acancel()
+	bctx, bcancel := context.WithCancel(ctx)
+	defer bcancel()
+
+	areports, adone := start(actx)
+
+	select {
+	case r := <-areports:
+		if r.AppScope != jbappscope || r.Checked != 1 {
+			t.Fatalf("unexpected report %v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the first replica to sweep")
+	}
+
+	//the second replica waits while the first holds the lock
+	breports, bdone := start(bctx)
+
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case r := <-breports:
+		t.Fatalf("unexpected report from the second replica %v", r)
+	default:
+	}
+
+	//and takes over once the first stops
+	acancel()
+
+	if err := <-adone; err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	select {
+	case <-breports:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the second replica to take over")
+	}
+
+	bcancel()
+
+	if err := <-bdone; err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
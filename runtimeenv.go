@@ -0,0 +1,292 @@
+package dfmgr
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	df "google.golang.org/api/dataflow/v1b3"
+)
+
+// RuntimeSettings are the dataflow runtime settings of a job definition
+type RuntimeSettings struct {
+	MaxWorkers              int64             `json:"maxWorkers,omitempty"`
+	NumWorkers              int64             `json:"numWorkers,omitempty"`
+	MachineType             string            `json:"machineType,omitempty"`
+	TempLocation            string            `json:"tempLocation,omitempty"`
+	Network                 string            `json:"network,omitempty"`
+	Subnetwork              string            `json:"subnetwork,omitempty"`
+	ServiceAccountEmail     string            `json:"serviceAccountEmail,omitempty"`
+	Zone                    string            `json:"zone,omitempty"`
+	WorkerRegion            string            `json:"workerRegion,omitempty"`
+	IPConfiguration         string            `json:"ipConfiguration,omitempty"`
+	KmsKeyName              string            `json:"kmsKeyName,omitempty"`
+	AdditionalExperiments   []string          `json:"additionalExperiments,omitempty"`
+	AdditionalUserLabels    map[string]string `json:"additionalUserLabels,omitempty"`
+	BypassTempDirValidation bool              `json:"bypassTempDirValidation,omitempty"`
+	EnableStreamingEngine   bool              `json:"enableStreamingEngine,omitempty"`
+}
+
+// environment converts the settings for a classic template launch
+func (rs *RuntimeSettings) environment() *df.RuntimeEnvironment {
+	return &df.RuntimeEnvironment{
+		MaxWorkers:              rs.MaxWorkers,
+		NumWorkers:              rs.NumWorkers,
+		MachineType:             rs.MachineType,
+		TempLocation:            rs.TempLocation,
+		Network:                 rs.Network,
+		Subnetwork:              rs.Subnetwork,
+		ServiceAccountEmail:     rs.ServiceAccountEmail,
+		Zone:                    rs.Zone,
+		WorkerRegion:            rs.WorkerRegion,
+		IpConfiguration:         rs.IPConfiguration,
+		KmsKeyName:              rs.KmsKeyName,
+		AdditionalExperiments:   rs.AdditionalExperiments,
+		AdditionalUserLabels:    rs.AdditionalUserLabels,
+		BypassTempDirValidation: rs.BypassTempDirValidation,
+		EnableStreamingEngine:   rs.EnableStreamingEngine,
+	}
+}
+
+// runtimeMap converts the settings to the runtimeenvironment map of a legacy job definition
+func (rs *RuntimeSettings) runtimeMap() map[string]string {
+	env := make(map[string]string)
+
+	set := func(k, v string) {
+		if v != "" {
+			env[k] = v
+		}
+	}
+
+	if rs.MaxWorkers != 0 {
+		env["maxWorkers"] = strconv.FormatInt(rs.MaxWorkers, 10)
+	}
+
+	if rs.NumWorkers != 0 {
+		env["numWorkers"] = strconv.FormatInt(rs.NumWorkers, 10)
+	}
+
+	set("machineType", rs.MachineType)
+	set("tempLocation", rs.TempLocation)
+	set("network", rs.Network)
+	set("subnetwork", rs.Subnetwork)
+	set("serviceAccountEmail", rs.ServiceAccountEmail)
+	set("zone", rs.Zone)
+	set("workerRegion", rs.WorkerRegion)
+	set("ipConfiguration", rs.IPConfiguration)
+	set("kmsKeyName", rs.KmsKeyName)
+	if len(rs.AdditionalExperiments) > 0 {
+		experiments := make([]string, 0, len(rs.AdditionalExperiments))
+		for _, item := range rs.AdditionalExperiments {
+			experiments = append(experiments, runtimeEscaper.Replace(item))
+		}
+
+		env["additionalExperiments"] = strings.Join(experiments, ",")
+	}
+
+	if len(rs.AdditionalUserLabels) > 0 {
+		labels := make([]string, 0, len(rs.AdditionalUserLabels))
+		for k, v := range rs.AdditionalUserLabels {
+			labels = append(labels, runtimeEscaper.Replace(k)+"="+runtimeEscaper.Replace(v))
+		}
+		slices.Sort(labels)
+
+		env["additionalUserLabels"] = strings.Join(labels, ",")
+	}
+
+	if rs.BypassTempDirValidation {
+		env["bypassTempDirValidation"] = "true"
+	}
+
+	if rs.EnableStreamingEngine {
+		env["enableStreamingEngine"] = "true"
+	}
+
+	return env
+}
+
+// newRuntimeSettings converts a legacy job definition's runtimeenvironment, rejecting keys which do not map to a runtime setting.
+// Numbers and booleans are strings, additionalExperiments is a comma separated list and additionalUserLabels is a comma separated list of key=value pairs.
+// A backslash escapes the next character, so that an item may contain a comma (or a label an equals sign).
+func newRuntimeSettings(env map[string]string) (*RuntimeSettings, error) {
+	var (
+		unknown []string
+		invalid error
+	)
+
+	rs := applyRuntimeSettings(env, func(key string, err error) {
+		if errors.Is(err, ErrUnknownRuntimeKey) {
+			unknown = append(unknown, strconv.Quote(key))
+			return
+		}
+
+		if invalid == nil {
+			invalid = fmt.Errorf("%w: %s %q: %v", ErrInvalidRuntimeValue, key, env[key], err)
+		}
+	})
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRuntimeKey, strings.Join(unknown, ", "))
+	}
+
+	if invalid != nil {
+		return nil, invalid
+	}
+
+	return rs, nil
+}
+
+// applyRuntimeSettings converts a legacy job definition's runtimeenvironment, calling problem for each key which does not map to a
+// runtime setting (ErrUnknownRuntimeKey) or whose value cannot be converted. The keys are applied in a fixed order, so that
+// the problems are always reported in the same order.
+func applyRuntimeSettings(env map[string]string, problem func(key string, err error)) *RuntimeSettings {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	rs := &RuntimeSettings{}
+
+	for _, k := range keys {
+		if err := rs.set(k, env[k]); err != nil {
+			problem(k, err)
+		}
+	}
+
+	return rs
+}
+
+// set applies a runtimeenvironment key to the settings, returning ErrUnknownRuntimeKey if it does not map to a setting
+func (rs *RuntimeSettings) set(key, v string) (err error) {
+	switch key {
+	case "maxWorkers":
+		rs.MaxWorkers, err = strconv.ParseInt(v, 10, 64)
+	case "numWorkers":
+		rs.NumWorkers, err = strconv.ParseInt(v, 10, 64)
+	case "machineType":
+		rs.MachineType = v
+	case "tempLocation":
+		rs.TempLocation = v
+	case "network":
+		rs.Network = v
+	case "subnetwork":
+		rs.Subnetwork = v
+	case "serviceAccountEmail":
+		rs.ServiceAccountEmail = v
+	case "zone":
+		rs.Zone = v
+	case "workerRegion":
+		rs.WorkerRegion = v
+	case "ipConfiguration":
+		rs.IPConfiguration = v
+	case "kmsKeyName":
+		rs.KmsKeyName = v
+	case "additionalExperiments":
+		rs.AdditionalExperiments = nil
+		for _, item := range splitRuntimeList(v) {
+			rs.AdditionalExperiments = append(rs.AdditionalExperiments, unescapeRuntimeValue(item))
+		}
+	case "additionalUserLabels":
+		rs.AdditionalUserLabels, err = parseRuntimeLabels(v)
+	case "bypassTempDirValidation":
+		rs.BypassTempDirValidation, err = strconv.ParseBool(v)
+	case "enableStreamingEngine":
+		rs.EnableStreamingEngine, err = strconv.ParseBool(v)
+	default:
+		return ErrUnknownRuntimeKey
+	}
+
+	return err
+}
+
+// newFlexRuntimeEnvironment converts a runtime environment for a flex template launch, which cannot bypass the temp dir validation
+func newFlexRuntimeEnvironment(rn *df.RuntimeEnvironment) (*df.FlexTemplateRuntimeEnvironment, error) {
+	if rn.BypassTempDirValidation {
+		return nil, fmt.Errorf("%w: bypassTempDirValidation is not supported by flex templates", ErrInvalidRuntimeValue)
+	}
+
+	return &df.FlexTemplateRuntimeEnvironment{
+		MaxWorkers:            rn.MaxWorkers,
+		NumWorkers:            rn.NumWorkers,
+		MachineType:           rn.MachineType,
+		TempLocation:          rn.TempLocation,
+		Network:               rn.Network,
+		Subnetwork:            rn.Subnetwork,
+		ServiceAccountEmail:   rn.ServiceAccountEmail,
+		Zone:                  rn.Zone,
+		WorkerRegion:          rn.WorkerRegion,
+		IpConfiguration:       rn.IpConfiguration,
+		KmsKeyName:            rn.KmsKeyName,
+		AdditionalExperiments: rn.AdditionalExperiments,
+		AdditionalUserLabels:  rn.AdditionalUserLabels,
+		EnableStreamingEngine: rn.EnableStreamingEngine,
+	}, nil
+}
+
+// runtimeEscaper escapes the separators in an additionalExperiments or additionalUserLabels item with a backslash
+var runtimeEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`)
+
+// splitRuntimeList splits a comma separated list, dropping empty items. Commas escaped by a backslash do not split the list,
+// and the items keep their escapes (see unescapeRuntimeValue).
+func splitRuntimeList(v string) []string {
+	var result []string
+	for more := true; more; {
+		var item string
+		item, v, more = cutRuntimeItem(v, ',')
+
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// unescapeRuntimeValue removes the backslash escapes from a list item
+func unescapeRuntimeValue(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			i++
+		}
+		sb.WriteByte(v[i])
+	}
+
+	return sb.String()
+}
+
+// cutRuntimeItem slices a list item around the first separator which is not escaped by a backslash
+func cutRuntimeItem(item string, sep byte) (before, after string, found bool) {
+	for i := 0; i < len(item); i++ {
+		switch item[i] {
+		case '\\':
+			i++
+		case sep:
+			return item[:i], item[i+1:], true
+		}
+	}
+
+	return item, "", false
+}
+
+// parseRuntimeLabels converts a comma separated list of key=value pairs
+func parseRuntimeLabels(v string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, item := range splitRuntimeList(v) {
+		k, lv, ok := cutRuntimeItem(item, '=')
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("label %q is not a key=value pair", item)
+		}
+
+		labels[unescapeRuntimeValue(strings.TrimSpace(k))] = unescapeRuntimeValue(strings.TrimSpace(lv))
+	}
+
+	return labels, nil
+}
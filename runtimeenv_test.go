@@ -0,0 +1,121 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	df "google.golang.org/api/dataflow/v1b3"
+)
+
+func Test_RuntimeEnvironment(t *testing.T) {
+	env := map[string]string{
+		"maxWorkers":              "4",
+		"numWorkers":              "2",
+		"machineType":             "n1-standard-2",
+		"tempLocation":            "gs://testproject/dataflow/temp/",
+		"network":                 "etl-net",
+		"subnetwork":              "regions/europe-west1/subnetworks/etl",
+		"serviceAccountEmail":     "etl@testproject.iam.gserviceaccount.com",
+		"zone":                    "europe-west1-b",
+		"workerRegion":            "europe-west1",
+		"ipConfiguration":         "WORKER_IP_PRIVATE",
+		"kmsKeyName":              "projects/testproject/locations/europe-west1/keyRings/etl/cryptoKeys/etl",
+		"additionalExperiments":   "use_runner_v2, shuffle_mode=service,",
+		"additionalUserLabels":    "team=etl, env = prod",
+		"bypassTempDirValidation": "false",
+		"enableStreamingEngine":   "true",
+	}
+
+	rs, err := newRuntimeSettings(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rn := rs.environment()
+
+	want := &df.RuntimeEnvironment{
+		MaxWorkers:            4,
+		NumWorkers:            2,
+		MachineType:           "n1-standard-2",
+		TempLocation:          "gs://testproject/dataflow/temp/",
+		Network:               "etl-net",
+		Subnetwork:            "regions/europe-west1/subnetworks/etl",
+		ServiceAccountEmail:   "etl@testproject.iam.gserviceaccount.com",
+		Zone:                  "europe-west1-b",
+		WorkerRegion:          "europe-west1",
+		IpConfiguration:       "WORKER_IP_PRIVATE",
+		KmsKeyName:            "projects/testproject/locations/europe-west1/keyRings/etl/cryptoKeys/etl",
+		AdditionalExperiments: []string{"use_runner_v2", "shuffle_mode=service"},
+		AdditionalUserLabels:  map[string]string{"team": "etl", "env": "prod"},
+		EnableStreamingEngine: true,
+	}
+
+	if !reflect.DeepEqual(rn, want) {
+		t.Fatalf("expected %+v, got %+v", want, rn)
+	}
+
+	flex, err := newFlexRuntimeEnvironment(rn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if flex.WorkerRegion != want.WorkerRegion || flex.AdditionalUserLabels["env"] != "prod" || !flex.EnableStreamingEngine {
+		t.Fatalf("unexpected flex environment %+v", flex)
+	}
+
+	tests := []struct {
+		env  map[string]string
+		want error
+	}{
+		{map[string]string{"maxworkers": "1", "diskSizeGb": "10"}, ErrUnknownRuntimeKey},
+		{map[string]string{"maxWorkers": "one"}, ErrInvalidRuntimeValue},
+		{map[string]string{"enableStreamingEngine": "maybe"}, ErrInvalidRuntimeValue},
+		{map[string]string{"additionalUserLabels": "team"}, ErrInvalidRuntimeValue},
+	}
+
+	for _, tt := range tests {
+		if _, err = newRuntimeSettings(tt.env); !errors.Is(err, tt.want) {
+			t.Fatalf("expected %v for %v, got %v", tt.want, tt.env, err)
+		}
+	}
+
+	//the error names every unknown key
+	_, err = newRuntimeSettings(tests[0].env)
+	if err.Error() != `unknown runtimeenvironment key: "diskSizeGb", "maxworkers"` {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	if _, err = newFlexRuntimeEnvironment(&df.RuntimeEnvironment{BypassTempDirValidation: true}); !errors.Is(err, ErrInvalidRuntimeValue) {
+		t.Fatalf("expected %v, got %v", ErrInvalidRuntimeValue, err)
+	}
+}
+func Test_JobStartRuntimeEnvironment(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, _ := newFakeMgr(ctx, t)
+
+	param := newTestJobParam()
+	param.RuntimeEnvironment["workerRegion"] = "europe-west2"
+	param.RuntimeEnvironment["additionalExperiments"] = "use_runner_v2"
+
+	if _, err := dfm.JobStart(ctx, jbappscope, jobtype, param); err != nil {
+		t.Fatal(err)
+	}
+
+	launched := fc.Launched()
+	if len(launched) != 1 || launched[0].Environment.WorkerRegion != "europe-west2" || launched[0].Environment.AdditionalExperiments[0] != "use_runner_v2" {
+		t.Fatalf("unexpected launch request %v", launched)
+	}
+
+	//unknown keys are rejected before launching
+	param.RuntimeEnvironment["workerRegoin"] = "europe-west2"
+
+	if _, err := dfm.JobStart(ctx, jbappscope, jobtype, param); !errors.Is(err, ErrUnknownRuntimeKey) {
+		t.Fatalf("expected %v, got %v", ErrUnknownRuntimeKey, err)
+	}
+
+	if len(fc.Launched()) != 1 {
+		t.Fatal("expected no further launches")
+	}
+}
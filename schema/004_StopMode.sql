@@ -0,0 +1,186 @@
+/*********************************************************************
+Name: 004_StopMode
+Notes:
+    records how a job was asked to stop (cancel or drain)
+    run after 003_Functions.sql, the get functions are replaced to return stopmode
+*********************************************************************/
+
+ALTER TABLE public.jobcontrol ADD COLUMN IF NOT EXISTS stopmode character varying(32) COLLATE pg_catalog."default" NULL;
+
+
+CREATE OR REPLACE FUNCTION public.set_jobstopmode(
+    in_jobid character varying(255),
+    in_stopmode character varying(32))
+    RETURNS void
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: set_jobstopmode
+Auth: DF
+Date: 18.10.2026
+Notes:
+    Records how a job was asked to stop
+*********************************************************************/
+BEGIN
+    update public.jobcontrol jc
+        set stopmode=in_stopmode,
+		lasttouched=now()
+    where jc.jobid=in_jobid;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.set_jobstopmode(character varying,character varying) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.set_jobstopmode(character varying,character varying) to dataflowcontroluser;
+
+
+CREATE OR REPLACE FUNCTION public.get_jobcontrol(
+    in_jobid character varying(255))
+    RETURNS JSONB
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: get_jobcontrol
+Auth: DF
+Date: 11.04.2019
+Notes:
+    Returns a jobcontrol record
+*********************************************************************/
+DECLARE 
+    l_result jsonb;
+BEGIN
+    select row_to_json(dat1)
+    into l_result
+    from
+    (
+        select
+            jc.jobcontrolid,
+            jc.appscope,
+            jc.jobid,
+            jc.jobtype,
+            jc.laststatus,
+            jc.stopmode,
+            jc.createddate
+        from public.jobcontrol jc
+        where jc.jobid=in_jobid
+    ) dat1;
+
+    return l_result;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.get_jobcontrol(character varying) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.get_jobcontrol(character varying) to dataflowcontroluser;
+
+
+CREATE OR REPLACE FUNCTION public.get_appscopejobcontrol(
+	in_appscope character varying(255),
+    in_jobtype character varying(255) default null,
+    in_laststatus character varying(255) default null)
+    RETURNS JSONB
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: get_appscopejobcontrol
+Auth: DF
+Date: 11.04.2019
+Notes:
+    Returns a jobcontrol record
+*********************************************************************/
+DECLARE 
+    l_result jsonb;
+BEGIN
+	select json_agg(row_to_json(uac))
+	into l_result
+	from
+	(
+		select
+			jc.jobcontrolid,
+			jc.appscope,
+			jc.jobid,
+			jc.jobtype,
+			jc.laststatus,
+			jc.stopmode,
+			jc.createddate,
+			jc.lasttouched
+		from public.jobcontrol jc
+		where jc.appscope=in_appscope
+        and (nullif(in_jobtype,'') is null or jc.jobtype=in_jobtype)
+        and (nullif(in_laststatus,'') is null or jc.laststatus=in_laststatus)
+	) uac;
+
+	return l_result;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.get_appscopejobcontrol(character varying,character varying,character varying) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.get_appscopejobcontrol(character varying,character varying,character varying) to dataflowcontroluser;
+
+
+CREATE OR REPLACE FUNCTION public.get_latestappscopejobcontrol(
+	in_appscope character varying(255),
+    in_jobtype character varying(255),
+    in_limit int)
+    RETURNS JSONB
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: get_latestappscopejobcontrol
+Auth: DF
+Date: 11.04.2019
+Notes:
+    Returns the latest jobcontrol record for an appscope and jobtype
+*********************************************************************/
+DECLARE 
+    l_result jsonb;
+    l_dtlimit timestamp;
+BEGIN
+    select now() - interval '24 hours'
+    into l_dtlimit;
+
+	select json_agg(row_to_json(uac))
+	into l_result
+	from
+	(
+		select
+			jc.jobcontrolid,
+			jc.appscope,
+			jc.jobid,
+			jc.jobtype,
+			jc.laststatus,
+			jc.stopmode,
+			jc.createddate,
+			jc.lasttouched
+		from public.jobcontrol jc
+		where jc.appscope=in_appscope
+        and jc.jobtype=in_jobtype
+        and jc.createddate >= l_dtlimit
+        order by jc.createddate desc
+        limit in_limit
+	) uac;
+
+	return l_result;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.get_latestappscopejobcontrol(character varying,character varying, int) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.get_latestappscopejobcontrol(character varying,character varying, int) to dataflowcontroluser;
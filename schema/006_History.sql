@@ -0,0 +1,232 @@
+/*********************************************************************
+Name: 006_History
+Notes:
+    adds jobcontrol_history, which records every distinct state observed for a job (and each stop request)
+    history rows are not removed by delete_jobcontrol or delete_jobcontrolarchive
+    run after 005_StopRequest.sql, set_jobcontrol, set_jobstatus and set_jobstop are replaced to write the history
+*********************************************************************/
+
+CREATE TABLE IF NOT EXISTS public.jobcontrol_history
+(
+    jobcontrolhistoryid bigserial not null,
+    appscope character varying(255) COLLATE pg_catalog."default" NOT NULL,
+    jobid character varying(255) COLLATE pg_catalog."default" NOT NULL,
+    jobtype character varying(255) COLLATE pg_catalog."default" NOT NULL,
+    status character varying(255) COLLATE pg_catalog."default" NOT NULL,
+    stopmode character varying(32) COLLATE pg_catalog."default" NULL,
+    stopreason character varying(1024) COLLATE pg_catalog."default" NULL,
+    stoppedby character varying(255) COLLATE pg_catalog."default" NULL,
+    observed timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT pk_jobcontrol_history PRIMARY KEY (jobcontrolhistoryid)
+);
+
+CREATE INDEX IF NOT EXISTS IX_jobcontrol_history_1 on public.jobcontrol_history(jobid,observed);
+CREATE INDEX IF NOT EXISTS IX_jobcontrol_history_2 on public.jobcontrol_history(appscope,jobtype,observed);
+
+ALTER TABLE public.jobcontrol_history OWNER to postgres;
+
+GRANT ALL ON TABLE public.jobcontrol_history to dataflowcontroluser;
+GRANT ALL ON SEQUENCE jobcontrol_history_jobcontrolhistoryid_seq to dataflowcontroluser;
+
+
+CREATE OR REPLACE FUNCTION public.set_jobcontrol(
+	in_appscope character varying(255),
+    in_jobid character varying(255),
+    in_jobtype character varying(255),
+    in_laststatus character varying(255))
+    RETURNS void
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+/*********************************************************************
+Name: set_jobcontrol
+Auth: DF
+Date: 11.04.2019
+Notes:
+    sets dataflow job metadata
+    18.10.2026 records new jobs and status changes in jobcontrol_history
+*********************************************************************/
+DECLARE 
+    l_laststatus character varying(255);
+BEGIN
+    --check if the record exists
+    select jc.laststatus
+    into l_laststatus
+    from public.jobcontrol jc
+    where jc.appscope=in_appscope
+    and jc.jobid=in_jobid;
+
+    --update it if it's already present
+    if found then
+        update public.jobcontrol jc
+            set jobtype=in_jobtype,
+                laststatus=in_laststatus,
+				lasttouched=now()
+        where jc.appscope=in_appscope
+        and jc.jobid=in_jobid;
+
+        if l_laststatus != in_laststatus then
+            insert into public.jobcontrol_history (appscope, jobid, jobtype, status)
+            values (in_appscope, in_jobid, in_jobtype, in_laststatus);
+        end if;
+
+        return;
+    end if;
+
+    --otherwise insert it
+    insert into public.jobcontrol
+    (
+        appscope,
+        jobid,
+        jobtype,
+        laststatus
+    )
+    values
+    (
+        in_appscope,
+        in_jobid,
+        in_jobtype,
+        in_laststatus
+    );
+
+    insert into public.jobcontrol_history (appscope, jobid, jobtype, status)
+    values (in_appscope, in_jobid, in_jobtype, in_laststatus);
+
+    --trim the job archive for this appscope
+    perform delete_jobcontrolarchive(in_appscope);
+END
+
+$BODY$;
+
+ALTER FUNCTION public.set_jobcontrol(character varying,character varying,character varying,character varying) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.set_jobcontrol(character varying,character varying,character varying,character varying) to dataflowcontroluser;
+
+
+CREATE OR REPLACE FUNCTION public.set_jobstatus(
+    in_jobid character varying(255),
+    in_status character varying(255))
+    RETURNS void
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: set_jobstatus
+Auth: DF
+Date: 06.05.2019
+Notes:
+    Sets a job status
+    18.10.2026 records status changes in jobcontrol_history
+*********************************************************************/
+BEGIN
+    update public.jobcontrol jc
+        set laststatus=in_status,
+		lasttouched=now()
+    where jc.jobid=in_jobid
+    and jc.laststatus!=in_status;
+
+    if found then
+        insert into public.jobcontrol_history (appscope, jobid, jobtype, status)
+        select jc.appscope, jc.jobid, jc.jobtype, jc.laststatus
+        from public.jobcontrol jc
+        where jc.jobid=in_jobid;
+    end if;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.set_jobstatus(character varying,character varying) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.set_jobstatus(character varying,character varying) to dataflowcontroluser;
+
+
+CREATE OR REPLACE FUNCTION public.set_jobstop(
+    in_jobid character varying(255),
+    in_status character varying(255),
+    in_stopmode character varying(32),
+    in_stopreason character varying(1024),
+    in_stoppedby character varying(255))
+    RETURNS void
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: set_jobstop
+Auth: DF
+Date: 18.10.2026
+Notes:
+    Records a stop request and the job status which followed it
+    the request is always written to jobcontrol_history, as the audit trail of why the job ended
+*********************************************************************/
+BEGIN
+    update public.jobcontrol jc
+        set laststatus=in_status,
+            stopmode=in_stopmode,
+            stopreason=nullif(in_stopreason,''),
+            stoppedby=nullif(in_stoppedby,''),
+            stoppeddate=now(),
+		lasttouched=now()
+    where jc.jobid=in_jobid;
+
+    insert into public.jobcontrol_history (appscope, jobid, jobtype, status, stopmode, stopreason, stoppedby)
+    select jc.appscope, jc.jobid, jc.jobtype, jc.laststatus, jc.stopmode, jc.stopreason, jc.stoppedby
+    from public.jobcontrol jc
+    where jc.jobid=in_jobid;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.set_jobstop(character varying,character varying,character varying,character varying,character varying) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.set_jobstop(character varying,character varying,character varying,character varying,character varying) to dataflowcontroluser;
+
+
+CREATE OR REPLACE FUNCTION public.get_jobhistory(
+    in_jobid character varying(255))
+    RETURNS JSONB
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: get_jobhistory
+Auth: DF
+Date: 18.10.2026
+Notes:
+    Returns the jobcontrol_history records for a job, oldest first
+*********************************************************************/
+DECLARE 
+    l_result jsonb;
+BEGIN
+	select json_agg(row_to_json(jh))
+	into l_result
+	from
+	(
+		select
+			h.appscope,
+			h.jobid,
+			h.jobtype,
+			h.status,
+			h.stopmode,
+			h.stopreason,
+			h.stoppedby,
+			h.observed
+		from public.jobcontrol_history h
+		where h.jobid=in_jobid
+        order by h.observed, h.jobcontrolhistoryid
+	) jh;
+
+	return l_result;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.get_jobhistory(character varying) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.get_jobhistory(character varying) to dataflowcontroluser;
@@ -0,0 +1,64 @@
+/*********************************************************************
+Name: 007_HistoryWindow
+Notes:
+    adds get_appscopejobhistory, which returns the history of the jobs first recorded within a time window
+    run after 006_History.sql
+*********************************************************************/
+
+CREATE OR REPLACE FUNCTION public.get_appscopejobhistory(
+	in_appscope character varying(255),
+    in_jobtype character varying(255),
+    in_from timestamp with time zone,
+    in_to timestamp with time zone)
+    RETURNS JSONB
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: get_appscopejobhistory
+Auth: DF
+Date: 18.10.2026
+Notes:
+    Returns the jobcontrol_history records for the jobs in an appscope (and jobtype, which can be empty string)
+    whose first record falls within [in_from, in_to), oldest first
+*********************************************************************/
+DECLARE 
+    l_result jsonb;
+BEGIN
+	select json_agg(row_to_json(jh))
+	into l_result
+	from
+	(
+		select
+			h.appscope,
+			h.jobid,
+			h.jobtype,
+			h.status,
+			h.stopmode,
+			h.stopreason,
+			h.stoppedby,
+			h.observed
+		from public.jobcontrol_history h
+		where h.jobid in
+        (
+            select w.jobid
+            from public.jobcontrol_history w
+            where w.appscope=in_appscope
+            and (nullif(in_jobtype,'') is null or w.jobtype=in_jobtype)
+            group by w.jobid
+            having min(w.observed) >= in_from
+            and min(w.observed) < in_to
+        )
+        order by h.observed, h.jobcontrolhistoryid
+	) jh;
+
+	return l_result;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.get_appscopejobhistory(character varying,character varying,timestamp with time zone,timestamp with time zone) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.get_appscopejobhistory(character varying,character varying,timestamp with time zone,timestamp with time zone) to dataflowcontroluser;
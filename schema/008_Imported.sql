@@ -0,0 +1,195 @@
+/*********************************************************************
+Name: 008_Imported
+Notes:
+    adds jobcontrol_history.imported, which marks the first history record of a job added by ImportJobs,
+    as the states the job passed through before it was imported were not observed
+    run after 007_HistoryWindow.sql, set_jobcontrol takes an optional in_imported argument and
+    get_jobhistory and get_appscopejobhistory return the flag
+*********************************************************************/
+
+ALTER TABLE public.jobcontrol_history ADD COLUMN IF NOT EXISTS imported boolean NOT NULL DEFAULT false;
+
+DROP FUNCTION IF EXISTS public.set_jobcontrol(character varying,character varying,character varying,character varying);
+
+CREATE OR REPLACE FUNCTION public.set_jobcontrol(
+	in_appscope character varying(255),
+    in_jobid character varying(255),
+    in_jobtype character varying(255),
+    in_laststatus character varying(255),
+    in_imported boolean DEFAULT false)
+    RETURNS void
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+/*********************************************************************
+Name: set_jobcontrol
+Auth: DF
+Date: 11.04.2019
+Notes:
+    sets dataflow job metadata
+    18.10.2026 records new jobs and status changes in jobcontrol_history
+    18.10.2026 marks the first history record of a job added by ImportJobs as imported
+*********************************************************************/
+DECLARE 
+    l_laststatus character varying(255);
+BEGIN
+    --check if the record exists
+    select jc.laststatus
+    into l_laststatus
+    from public.jobcontrol jc
+    where jc.appscope=in_appscope
+    and jc.jobid=in_jobid;
+
+    --update it if it's already present
+    if found then
+        update public.jobcontrol jc
+            set jobtype=in_jobtype,
+                laststatus=in_laststatus,
+				lasttouched=now()
+        where jc.appscope=in_appscope
+        and jc.jobid=in_jobid;
+
+        if l_laststatus != in_laststatus then
+            insert into public.jobcontrol_history (appscope, jobid, jobtype, status)
+            values (in_appscope, in_jobid, in_jobtype, in_laststatus);
+        end if;
+
+        return;
+    end if;
+
+    --otherwise insert it
+    insert into public.jobcontrol
+    (
+        appscope,
+        jobid,
+        jobtype,
+        laststatus
+    )
+    values
+    (
+        in_appscope,
+        in_jobid,
+        in_jobtype,
+        in_laststatus
+    );
+
+    insert into public.jobcontrol_history (appscope, jobid, jobtype, status, imported)
+    values (in_appscope, in_jobid, in_jobtype, in_laststatus, in_imported);
+
+    --trim the job archive for this appscope
+    perform delete_jobcontrolarchive(in_appscope);
+END
+
+$BODY$;
+
+ALTER FUNCTION public.set_jobcontrol(character varying,character varying,character varying,character varying,boolean) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.set_jobcontrol(character varying,character varying,character varying,character varying,boolean) to dataflowcontroluser;
+
+CREATE OR REPLACE FUNCTION public.get_jobhistory(
+    in_jobid character varying(255))
+    RETURNS JSONB
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: get_jobhistory
+Auth: DF
+Date: 18.10.2026
+Notes:
+    Returns the jobcontrol_history records for a job, oldest first
+    18.10.2026 returns the imported flag
+*********************************************************************/
+DECLARE 
+    l_result jsonb;
+BEGIN
+	select json_agg(row_to_json(jh))
+	into l_result
+	from
+	(
+		select
+			h.appscope,
+			h.jobid,
+			h.jobtype,
+			h.status,
+			h.stopmode,
+			h.stopreason,
+			h.stoppedby,
+			h.imported,
+			h.observed
+		from public.jobcontrol_history h
+		where h.jobid=in_jobid
+        order by h.observed, h.jobcontrolhistoryid
+	) jh;
+
+	return l_result;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.get_jobhistory(character varying) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.get_jobhistory(character varying) to dataflowcontroluser;
+
+CREATE OR REPLACE FUNCTION public.get_appscopejobhistory(
+	in_appscope character varying(255),
+    in_jobtype character varying(255),
+    in_from timestamp with time zone,
+    in_to timestamp with time zone)
+    RETURNS JSONB
+    LANGUAGE 'plpgsql'
+
+    COST 100
+    VOLATILE 
+AS $BODY$
+
+/*********************************************************************
+Name: get_appscopejobhistory
+Auth: DF
+Date: 18.10.2026
+Notes:
+    Returns the jobcontrol_history records for the jobs in an appscope (and jobtype, which can be empty string)
+    whose first record falls within [in_from, in_to), oldest first
+    18.10.2026 returns the imported flag
+*********************************************************************/
+DECLARE 
+    l_result jsonb;
+BEGIN
+	select json_agg(row_to_json(jh))
+	into l_result
+	from
+	(
+		select
+			h.appscope,
+			h.jobid,
+			h.jobtype,
+			h.status,
+			h.stopmode,
+			h.stopreason,
+			h.stoppedby,
+			h.imported,
+			h.observed
+		from public.jobcontrol_history h
+		where h.jobid in
+        (
+            select w.jobid
+            from public.jobcontrol_history w
+            where w.appscope=in_appscope
+            and (nullif(in_jobtype,'') is null or w.jobtype=in_jobtype)
+            group by w.jobid
+            having min(w.observed) >= in_from
+            and min(w.observed) < in_to
+        )
+        order by h.observed, h.jobcontrolhistoryid
+	) jh;
+
+	return l_result;
+END
+
+$BODY$;
+
+ALTER FUNCTION public.get_appscopejobhistory(character varying,character varying,timestamp with time zone,timestamp with time zone) OWNER TO postgres;
+GRANT ALL ON FUNCTION public.get_appscopejobhistory(character varying,character varying,timestamp with time zone,timestamp with time zone) to dataflowcontroluser;
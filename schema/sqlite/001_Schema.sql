@@ -0,0 +1,22 @@
+/*********************************************************************
+Name: jobcontrol (sqlite)
+Notes:
+    sqlite equivalent of schema/002_Schema.sql
+    timestamps are utc text in the form 2006-01-02T15:04:05.000000Z
+*********************************************************************/
+
+CREATE TABLE IF NOT EXISTS jobcontrol
+(
+    jobcontrolid integer not null,
+    appscope varchar(255) NOT NULL,
+    jobid varchar(255) NOT NULL,
+    jobtype varchar(255) NOT NULL,
+    laststatus varchar(255) NOT NULL,
+    createddate text NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    lasttouched text NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT pk_jobcontrol PRIMARY KEY (jobcontrolid AUTOINCREMENT),
+    CONSTRAINT uc_jobcontrol_1 UNIQUE (jobid),
+    CONSTRAINT uc_jobcontrol_2 UNIQUE (appscope,jobid)
+);
+
+CREATE INDEX IF NOT EXISTS IX_jobcontrol_1 on jobcontrol(appscope,jobtype,laststatus,jobid);
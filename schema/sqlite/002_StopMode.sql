@@ -0,0 +1,7 @@
+/*********************************************************************
+Name: 002_StopMode (sqlite)
+Notes:
+    sqlite equivalent of schema/004_StopMode.sql
+*********************************************************************/
+
+ALTER TABLE jobcontrol ADD COLUMN stopmode varchar(32) NULL;
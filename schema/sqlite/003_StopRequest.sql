@@ -0,0 +1,9 @@
+/*********************************************************************
+Name: 003_StopRequest (sqlite)
+Notes:
+    sqlite equivalent of schema/005_StopRequest.sql
+*********************************************************************/
+
+ALTER TABLE jobcontrol ADD COLUMN stopreason varchar(1024) NULL;
+ALTER TABLE jobcontrol ADD COLUMN stoppedby varchar(255) NULL;
+ALTER TABLE jobcontrol ADD COLUMN stoppeddate text NULL;
@@ -0,0 +1,23 @@
+/*********************************************************************
+Name: 004_History (sqlite)
+Notes:
+    sqlite equivalent of the jobcontrol_history table in schema/006_History.sql
+    history rows are written by SqliteMgr and are not removed with their jobcontrol rows
+*********************************************************************/
+
+CREATE TABLE IF NOT EXISTS jobcontrol_history
+(
+    jobcontrolhistoryid integer not null,
+    appscope varchar(255) NOT NULL,
+    jobid varchar(255) NOT NULL,
+    jobtype varchar(255) NOT NULL,
+    status varchar(255) NOT NULL,
+    stopmode varchar(32) NULL,
+    stopreason varchar(1024) NULL,
+    stoppedby varchar(255) NULL,
+    observed text NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT pk_jobcontrol_history PRIMARY KEY (jobcontrolhistoryid AUTOINCREMENT)
+);
+
+CREATE INDEX IF NOT EXISTS IX_jobcontrol_history_1 on jobcontrol_history(jobid,observed);
+CREATE INDEX IF NOT EXISTS IX_jobcontrol_history_2 on jobcontrol_history(appscope,jobtype,observed);
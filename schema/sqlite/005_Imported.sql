@@ -0,0 +1,7 @@
+/*********************************************************************
+Name: 005_Imported (sqlite)
+Notes:
+    sqlite equivalent of schema/008_Imported.sql
+*********************************************************************/
+
+ALTER TABLE jobcontrol_history ADD COLUMN imported integer NOT NULL DEFAULT 0;
@@ -0,0 +1,547 @@
+package dfmgr
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"time"
+
+	sqlite "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//sqliteSchema holds the numbered schema scripts, which are applied in order by migrateSqlite
+//
+//go:embed schema/sqlite/*.sql
+var sqliteSchema embed.FS
+
+//sqliteJobColumns are the jobcontrol columns read by scanSqliteJobs, followed by lasttouched
+const sqliteJobColumns = "appscope, jobid, jobtype, laststatus, stopmode, stopreason, stoppedby, stoppeddate, createddate"
+
+//sqliteHistoryColumns are the jobcontrol_history columns read by scanSqliteHistory
+const sqliteHistoryColumns = "appscope, jobid, jobtype, status, stopmode, stopreason, stoppedby, imported, observed"
+
+//sqliteTimeLayout is a fixed width utc layout, so that stored timestamps compare correctly as text
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+//SqliteMgr handles interactions with an embedded sqlite db store
+type SqliteMgr struct {
+	ds     *sql.DB
+	log    *slog.Logger
+	now    func() time.Time
+	window time.Duration
+}
+
+//NewSqliteMgr creates a new manager for the sqlite db at dsn (e.g. a file path or file: uri), creating the schema if required
+//and logging to logger (or discarding events, if nil)
+func NewSqliteMgr(ctx context.Context, dsn string, logger *slog.Logger) (*SqliteMgr, error) {
+	if logger == nil {
+		logger = newLogger(false)
+	}
+
+	logger = logger.With("mgr", "SqliteMgr")
+	logger.DebugContext(ctx, "start", "op", "NewSqliteMgr")
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	//sqlite serialises writers, so share a single connection (this also keeps :memory: dbs consistent)
+	db.SetMaxOpenConns(1)
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err = migrateSqlite(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	sq1 := &SqliteMgr{
+		ds:     db,
+		log:    logger,
+		now:    time.Now,
+		window: 24 * time.Hour,
+	}
+
+	logger.DebugContext(ctx, "end", "op", "NewSqliteMgr")
+
+	return sq1, nil
+}
+
+//migrateSqlite applies the schema scripts which have not yet been run, tracking the last one applied in user_version
+func migrateSqlite(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(sqliteSchema, "schema/sqlite/*.sql")
+	if err != nil {
+		return err
+	}
+
+	var version int
+	if err = db.QueryRowContext(ctx, "pragma user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	//scripts are numbered from 1, in name order
+	for i := version; i < len(names); i++ {
+		script, err := sqliteSchema.ReadFile(names[i])
+		if err != nil {
+			return err
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, string(script)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("%s: %v", names[i], err)
+		}
+
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf("pragma user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Close closes the underlying db
+func (sqm *SqliteMgr) Close() error {
+	return sqm.ds.Close()
+}
+
+//SaveJob saves a job
+func (sqm *SqliteMgr) SaveJob(ctx context.Context, mdp *DsJob) (err error) {
+	op := beginOp(ctx, sqm.log, "SaveJob", "appscope", mdp.AppScope, "jobid", mdp.JobID, "jobtype", mdp.JobType, "jobstate", mdp.LastStatus)
+	defer op.end(&err)
+
+	now := sqm.now()
+
+	tx, err := sqm.ds.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	//note the current status so that only a change is recorded in the history
+	var laststatus sql.NullString
+	err = tx.QueryRowContext(ctx, "select laststatus from jobcontrol where appscope=?1 and jobid=?2", mdp.AppScope, mdp.JobID).Scan(&laststatus)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	//update the record if it's already present
+	rs, err := tx.ExecContext(ctx, `update jobcontrol
+		set jobtype=?1, laststatus=?2, lasttouched=?3
+		where appscope=?4 and jobid=?5`,
+		mdp.JobType, string(mdp.LastStatus), sqliteTime(now), mdp.AppScope, mdp.JobID)
+	if err != nil {
+		return err
+	}
+
+	ct, err := rs.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	//otherwise insert it and trim the job archive for this appscope
+	if ct == 0 {
+		_, err = tx.ExecContext(ctx, `insert into jobcontrol (appscope, jobid, jobtype, laststatus, createddate, lasttouched)
+			values (?1, ?2, ?3, ?4, ?5, ?5)`,
+			mdp.AppScope, mdp.JobID, mdp.JobType, string(mdp.LastStatus), sqliteTime(now))
+		if isSqliteUniqueViolation(err) {
+			//the jobid is already registered to another appscope
+			return ErrJobIDConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "delete from jobcontrol where appscope=?1 and createddate < ?2", mdp.AppScope, sqliteTime(now.Add(-sqm.window)))
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct == 0 || laststatus.String != string(mdp.LastStatus) {
+		if err = addSqliteHistory(ctx, tx, mdp.JobID, now, false, ct == 0 && mdp.Imported); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//SetJobStatus sets a job status
+func (sqm *SqliteMgr) SetJobStatus(ctx context.Context, jobid string, jobstate JobState) (err error) {
+	op := beginOp(ctx, sqm.log, "SetJobStatus", "jobid", jobid, "jobstate", jobstate)
+	defer op.end(&err)
+
+	now := sqm.now()
+
+	tx, err := sqm.ds.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	//only touch the job if the status has changed
+	rs, err := tx.ExecContext(ctx, "update jobcontrol set laststatus=?1, lasttouched=?2 where jobid=?3 and laststatus!=?1", string(jobstate), sqliteTime(now), jobid)
+	if err != nil {
+		return err
+	}
+
+	ct, err := rs.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if ct > 0 {
+		if err = addSqliteHistory(ctx, tx, jobid, now, false, false); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//SetJobStop records a stop request and the job status which followed it
+func (sqm *SqliteMgr) SetJobStop(ctx context.Context, jobid string, jobstate JobState, req *StopRequest) (err error) {
+	op := beginOp(ctx, sqm.log, "SetJobStop", "jobid", jobid, "jobstate", jobstate, "stopmode", req.Mode, "stoppedby", req.RequestedBy)
+	defer op.end(&err)
+
+	now := sqm.now()
+
+	tx, err := sqm.ds.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `update jobcontrol
+		set laststatus=?1, stopmode=?2, stopreason=nullif(?3,''), stoppedby=nullif(?4,''), stoppeddate=?5, lasttouched=?5
+		where jobid=?6`,
+		string(jobstate), string(req.Mode), req.Reason, req.RequestedBy, sqliteTime(now), jobid)
+	if err != nil {
+		return err
+	}
+
+	//stop requests are always recorded
+	if err = addSqliteHistory(ctx, tx, jobid, now, true, false); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//GetJobHistory gets the states recorded for a job, oldest first
+func (sqm *SqliteMgr) GetJobHistory(ctx context.Context, jobid string) (_ []*JobHistory, err error) {
+	op := beginOp(ctx, sqm.log, "GetJobHistory", "jobid", jobid)
+	defer op.end(&err)
+
+	rows, err := sqm.ds.QueryContext(ctx, `select `+sqliteHistoryColumns+`
+		from jobcontrol_history where jobid=?1 order by observed, jobcontrolhistoryid`, jobid)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := scanSqliteHistory(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+//GetAppScopeJobHistory gets the history of the jobs for an appscope (and jobtype, which can be empty string) which were first recorded within [from, to), oldest first
+func (sqm *SqliteMgr) GetAppScopeJobHistory(ctx context.Context, appscope, jobtype string, from, to time.Time) (_ []*JobHistory, err error) {
+	op := beginOp(ctx, sqm.log, "GetAppScopeJobHistory", "appscope", appscope, "jobtype", jobtype, "from", from, "to", to)
+	defer op.end(&err)
+
+	rows, err := sqm.ds.QueryContext(ctx, `select `+sqliteHistoryColumns+`
+		from jobcontrol_history
+		where jobid in
+		(
+			select jobid
+			from jobcontrol_history
+			where appscope=?1
+			and (nullif(?2,'') is null or jobtype=?2)
+			group by jobid
+			having min(observed) >= ?3
+			and min(observed) < ?4
+		)
+		order by observed, jobcontrolhistoryid`, appscope, jobtype, sqliteTime(from), sqliteTime(to))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := scanSqliteHistory(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+//GetJob gets a specific job
+func (sqm *SqliteMgr) GetJob(ctx context.Context, jobid string) (_ *DsJob, err error) {
+	op := beginOp(ctx, sqm.log, "GetJob", "jobid", jobid)
+	defer op.end(&err)
+
+	//as with get_jobcontrol, lasttouched is not returned
+	rows, err := sqm.ds.QueryContext(ctx, "select "+sqliteJobColumns+", null from jobcontrol where jobid=?1", jobid)
+	if err != nil {
+		return nil, err
+	}
+
+	jbs, err := scanSqliteJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return jbs[0], nil
+}
+
+//GetAppScopeJobs gets the Jobs for a specified appscope, jobtype and jobstate (latter two can be empty string)
+func (sqm *SqliteMgr) GetAppScopeJobs(ctx context.Context, appscope, jobtype string, jobstate JobState) (_ []*DsJob, err error) {
+	op := beginOp(ctx, sqm.log, "GetAppScopeJobs", "appscope", appscope, "jobtype", jobtype, "jobstate", jobstate)
+	defer op.end(&err)
+
+	rows, err := sqm.ds.QueryContext(ctx, `select `+sqliteJobColumns+`, lasttouched
+		from jobcontrol
+		where appscope=?1
+		and (nullif(?2,'') is null or jobtype=?2)
+		and (nullif(?3,'') is null or laststatus=?3)
+		order by jobcontrolid`, appscope, jobtype, string(jobstate))
+	if err != nil {
+		return nil, err
+	}
+
+	jbs, err := scanSqliteJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return jbs, nil
+}
+
+//GetLatestAppScopeJob gets the lastest Job for a specified appscope
+func (sqm *SqliteMgr) GetLatestAppScopeJob(ctx context.Context, appscope, jobtype string, limit int) (_ []*DsJob, err error) {
+	op := beginOp(ctx, sqm.log, "GetLatestAppScopeJob", "appscope", appscope, "jobtype", jobtype, "limit", limit)
+	defer op.end(&err)
+
+	//sqlite treats a negative limit as no limit, so match the postgres behaviour
+	if limit < 0 {
+		return nil, ErrNegativeLimit
+	}
+
+	rows, err := sqm.ds.QueryContext(ctx, `select `+sqliteJobColumns+`, lasttouched
+		from jobcontrol
+		where appscope=?1
+		and jobtype=?2
+		and createddate >= ?3
+		order by createddate desc
+		limit ?4`, appscope, jobtype, sqliteTime(sqm.now().Add(-sqm.window)), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	jbs, err := scanSqliteJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return jbs, nil
+}
+
+//GetAppScopeJobCount gets the count of Jobs for a specified appscope
+func (sqm *SqliteMgr) GetAppScopeJobCount(ctx context.Context, appscope, jobtype string, jobstate JobState) (_ int64, err error) {
+	op := beginOp(ctx, sqm.log, "GetAppScopeJobCount", "appscope", appscope, "jobtype", jobtype, "jobstate", jobstate)
+	defer op.end(&err)
+
+	var result int64
+	err = sqm.ds.QueryRowContext(ctx, `select count(1)
+		from jobcontrol
+		where appscope=?1
+		and (nullif(?2,'') is null or jobtype=?2)
+		and (nullif(?3,'') is null or laststatus=?3)`, appscope, jobtype, string(jobstate)).Scan(&result)
+	if err != nil {
+		return -1, err
+	}
+
+	return result, nil
+}
+
+//DeleteJob clears a job
+func (sqm *SqliteMgr) DeleteJob(ctx context.Context, appscope, jobid string) (err error) {
+	op := beginOp(ctx, sqm.log, "DeleteJob", "appscope", appscope, "jobid", jobid)
+	defer op.end(&err)
+
+	_, err = sqm.ds.ExecContext(ctx, "delete from jobcontrol where appscope=?1 and jobid=?2", appscope, jobid)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteJobArchive clears the job archive (older than 24 hours)
+func (sqm *SqliteMgr) DeleteJobArchive(ctx context.Context, appscope string) (err error) {
+	op := beginOp(ctx, sqm.log, "DeleteJobArchive", "appscope", appscope)
+	defer op.end(&err)
+
+	_, err = sqm.ds.ExecContext(ctx, "delete from jobcontrol where appscope=?1 and createddate < ?2", appscope, sqliteTime(sqm.now().Add(-sqm.window)))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//isSqliteUniqueViolation reports whether err is a unique constraint failure
+func isSqliteUniqueViolation(err error) bool {
+	var se *sqlite.Error
+	return errors.As(err, &se) && se.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+//sqliteTime formats a timestamp for storage
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+//scanSqliteHistory reads a jobcontrol_history result set, returning ErrNoDataFound if it is empty
+func scanSqliteHistory(rows *sql.Rows) ([]*JobHistory, error) {
+	defer rows.Close()
+
+	var result []*JobHistory
+	for rows.Next() {
+		var (
+			h                               JobHistory
+			stopmode, stopreason, stoppedby sql.NullString
+			observed                        string
+		)
+
+		if err := rows.Scan(&h.AppScope, &h.JobID, &h.JobType, &h.Status, &stopmode, &stopreason, &stoppedby, &h.Imported, &observed); err != nil {
+			return nil, err
+		}
+
+		dt, err := time.Parse(sqliteTimeLayout, observed)
+		if err != nil {
+			return nil, err
+		}
+
+		h.StopMode = StopMode(stopmode.String)
+		h.StopReason = stopreason.String
+		h.StoppedBy = stoppedby.String
+		h.Observed = &dt
+
+		result = append(result, &h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoDataFound
+	}
+
+	return result, nil
+}
+
+//scanSqliteJobs reads a jobcontrol result set, returning ErrNoDataFound if it is empty
+func scanSqliteJobs(rows *sql.Rows) ([]*DsJob, error) {
+	defer rows.Close()
+
+	var jbs []*DsJob
+	for rows.Next() {
+		var (
+			jb          DsJob
+			stopmode    sql.NullString
+			stopreason  sql.NullString
+			stoppedby   sql.NullString
+			stoppeddate sql.NullString
+			createddate string
+			lasttouched sql.NullString
+		)
+
+		if err := rows.Scan(&jb.AppScope, &jb.JobID, &jb.JobType, &jb.LastStatus, &stopmode, &stopreason, &stoppedby, &stoppeddate, &createddate, &lasttouched); err != nil {
+			return nil, err
+		}
+		jb.StopMode = StopMode(stopmode.String)
+		jb.StopReason = stopreason.String
+		jb.StoppedBy = stoppedby.String
+
+		if stoppeddate.Valid {
+			dt, err := time.Parse(sqliteTimeLayout, stoppeddate.String)
+			if err != nil {
+				return nil, err
+			}
+			jb.StoppedDate = &dt
+		}
+
+		dt, err := time.Parse(sqliteTimeLayout, createddate)
+		if err != nil {
+			return nil, err
+		}
+		jb.CreatedDate = &dt
+
+		if lasttouched.Valid {
+			dt, err := time.Parse(sqliteTimeLayout, lasttouched.String)
+			if err != nil {
+				return nil, err
+			}
+			jb.LastTouched = &dt
+		}
+
+		jbs = append(jbs, &jb)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	//if the result is empty, return the appropriate message
+	if len(jbs) == 0 {
+		return nil, ErrNoDataFound
+	}
+
+	return jbs, nil
+}
+
+//addSqliteHistory copies a job's current status into jobcontrol_history, with its stop request if stop is set and marked as
+//imported if imported is set
+func addSqliteHistory(ctx context.Context, tx *sql.Tx, jobid string, now time.Time, stop, imported bool) error {
+	_, err := tx.ExecContext(ctx, `insert into jobcontrol_history (appscope, jobid, jobtype, status, stopmode, stopreason, stoppedby, imported, observed)
+		select appscope, jobid, jobtype, laststatus,
+			case when ?3 then stopmode end, case when ?3 then stopreason end, case when ?3 then stoppedby end, ?4, ?2
+		from jobcontrol where jobid=?1`,
+		jobid, sqliteTime(now), stop, imported)
+
+	return err
+}
@@ -0,0 +1,446 @@
+package dfmgr
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//newTestSqliteMgr returns an in-memory SqliteMgr with a controllable clock
+func newTestSqliteMgr(ctx context.Context, t *testing.T) (*SqliteMgr, *time.Time) {
+	sq, err := NewSqliteMgr(ctx, ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sq.Close() })
+
+	now := time.Date(2019, 4, 11, 12, 0, 0, 0, time.UTC)
+	sq.now = func() time.Time { return now }
+
+	return sq, &now
+}
+
+func Test_SqliteSaveJob(t *testing.T) {
+	ctx := context.Background()
+	sq, now := newTestSqliteMgr(ctx, t)
+
+	err := sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStatePending})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := *now
+	*now = now.Add(time.Minute)
+
+	//saving the same appscope/jobid updates the row
+	err = sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "othertype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jbs, err := sq.GetAppScopeJobs(ctx, appscope, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jbs) != 1 || jbs[0].JobType != "othertype" || jbs[0].LastStatus != CnstStateRunning {
+		t.Fatalf("job was not updated: %v", jbs)
+	}
+
+	if !jbs[0].CreatedDate.Equal(created) || !jbs[0].LastTouched.Equal(*now) {
+		t.Fatalf("unexpected timestamps: %v %v", jbs[0].CreatedDate, jbs[0].LastTouched)
+	}
+
+	//the jobid is unique across appscopes
+	err = sq.SaveJob(ctx, &DsJob{AppScope: "otherapp", JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != ErrJobIDConflict {
+		t.Fatalf("expected ErrJobIDConflict, got %v", err)
+	}
+}
+func Test_SqliteSetJobStatus(t *testing.T) {
+	ctx := context.Background()
+	sq, now := newTestSqliteMgr(ctx, t)
+
+	err := sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := *now
+	*now = now.Add(time.Minute)
+
+	//an unchanged status does not touch the job
+	err = sq.SetJobStatus(ctx, "123456", CnstStateRunning)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jbs, err := sq.GetAppScopeJobs(ctx, appscope, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !jbs[0].LastTouched.Equal(saved) {
+		t.Fatalf("expected lasttouched %v, got %v", saved, jbs[0].LastTouched)
+	}
+
+	err = sq.SetJobStatus(ctx, "123456", CnstStateDone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb, err := sq.GetJob(ctx, "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.LastStatus != CnstStateDone || jb.LastTouched != nil {
+		t.Fatalf("unexpected job %v", jb)
+	}
+}
+func Test_SqliteGetAppScopeJobs(t *testing.T) {
+	ctx := context.Background()
+	sq, _ := newTestSqliteMgr(ctx, t)
+
+	seed := []*DsJob{
+		{AppScope: appscope, JobID: "1", JobType: "typea", LastStatus: CnstStateRunning},
+		{AppScope: appscope, JobID: "2", JobType: "typeb", LastStatus: CnstStateRunning},
+		{AppScope: appscope, JobID: "3", JobType: "typea", LastStatus: CnstStateDone},
+		{AppScope: "otherapp", JobID: "4", JobType: "typea", LastStatus: CnstStateRunning},
+	}
+
+	for _, item := range seed {
+		if err := sq.SaveJob(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		jobtype  string
+		jobstate JobState
+		want     []string
+	}{
+		{"", "", []string{"1", "2", "3"}},
+		{"typea", "", []string{"1", "3"}},
+		{"", CnstStateRunning, []string{"1", "2"}},
+		{"typea", CnstStateDone, []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		jbs, err := sq.GetAppScopeJobs(ctx, appscope, tt.jobtype, tt.jobstate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(jbs) != len(tt.want) {
+			t.Fatalf("%q/%q: expected %v, got %d jobs", tt.jobtype, tt.jobstate, tt.want, len(jbs))
+		}
+
+		for i, item := range jbs {
+			if item.JobID != tt.want[i] {
+				t.Fatalf("%q/%q: expected %v, got %s at %d", tt.jobtype, tt.jobstate, tt.want, item.JobID, i)
+			}
+		}
+
+		ct, err := sq.GetAppScopeJobCount(ctx, appscope, tt.jobtype, tt.jobstate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ct != int64(len(tt.want)) {
+			t.Fatalf("%q/%q: expected count %d, got %d", tt.jobtype, tt.jobstate, len(tt.want), ct)
+		}
+	}
+
+	_, err := sq.GetAppScopeJobs(ctx, appscope, "typec", "")
+	if err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+}
+func Test_SqliteGetLatestAppScopeJob(t *testing.T) {
+	ctx := context.Background()
+	sq, now := newTestSqliteMgr(ctx, t)
+
+	for _, item := range []string{"1", "2", "3"} {
+		if err := sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: item, JobType: "typea", LastStatus: CnstStateDone}); err != nil {
+			t.Fatal(err)
+		}
+		*now = now.Add(time.Hour)
+	}
+
+	//job 1 falls outside the 24 hour window
+	*now = now.Add(21*time.Hour + time.Minute)
+
+	jbs, err := sq.GetLatestAppScopeJob(ctx, appscope, "typea", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jbs) != 2 || jbs[0].JobID != "3" || jbs[1].JobID != "2" {
+		t.Fatalf("unexpected jobs %v", jbs)
+	}
+
+	_, err = sq.GetLatestAppScopeJob(ctx, appscope, "typea", -1)
+	if err != ErrNegativeLimit {
+		t.Fatalf("expected ErrNegativeLimit, got %v", err)
+	}
+}
+func Test_SqliteDeleteJobArchive(t *testing.T) {
+	ctx := context.Background()
+	sq, now := newTestSqliteMgr(ctx, t)
+
+	for _, item := range []*DsJob{
+		{AppScope: appscope, JobID: "1", JobType: "typea", LastStatus: CnstStateDone},
+		{AppScope: appscope, JobID: "2", JobType: "typea", LastStatus: CnstStateDone},
+		{AppScope: "otherapp", JobID: "3", JobType: "typea", LastStatus: CnstStateDone},
+	} {
+		if err := sq.SaveJob(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := sq.DeleteJob(ctx, appscope, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	*now = now.Add(25 * time.Hour)
+
+	err = sq.DeleteJobArchive(ctx, appscope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ct, err := sq.GetAppScopeJobCount(ctx, appscope, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ct != 0 {
+		t.Fatalf("expected count 0, got %d", ct)
+	}
+
+	if _, err = sq.GetJob(ctx, "3"); err != nil {
+		t.Fatal(err)
+	}
+}
+func Test_SqliteSetJobStop(t *testing.T) {
+	ctx := context.Background()
+	sq, now := newTestSqliteMgr(ctx, t)
+
+	err := sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	*now = now.Add(time.Minute)
+
+	req := &StopRequest{Mode: StopModeDrain, Reason: "backfill complete", RequestedBy: "ops@example.com"}
+
+	err = sq.SetJobStop(ctx, "123456", CnstStateDraining, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jbs, err := sq.GetAppScopeJobs(ctx, appscope, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb := jbs[0]
+	if jb.LastStatus != CnstStateDraining || jb.StopMode != req.Mode || jb.StopReason != req.Reason || jb.StoppedBy != req.RequestedBy {
+		t.Fatalf("unexpected job %v", jb)
+	}
+
+	if !jb.LastTouched.Equal(*now) || jb.StoppedDate == nil || !jb.StoppedDate.Equal(*now) {
+		t.Fatalf("expected lasttouched and stoppeddate %v, got %v", *now, jb)
+	}
+
+	//unknown jobs are ignored
+	err = sq.SetJobStop(ctx, "654321", CnstStateCancelling, &StopRequest{Mode: StopModeCancel})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+func Test_SqliteMigrate(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "jobcontrol.db")
+
+	sq, err := NewSqliteMgr(ctx, dsn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sq.Close()
+
+	//reopening an existing db does not reapply the schema scripts
+	sq, err = NewSqliteMgr(ctx, dsn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sq.Close()
+
+	names, err := fs.Glob(sqliteSchema, "schema/sqlite/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var version int
+	if err = sq.ds.QueryRowContext(ctx, "pragma user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+
+	if version != len(names) {
+		t.Fatalf("expected version %d, got %d", len(names), version)
+	}
+
+	if _, err = sq.GetJob(ctx, "123456"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_SqliteJobHistory(t *testing.T) {
+	ctx := context.Background()
+	sq, now := newTestSqliteMgr(ctx, t)
+
+	err := sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStatePending})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//saving the job again with the same status is not recorded
+	err = sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "123456", JobType: "testerjobtype", LastStatus: CnstStatePending})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := *now
+	*now = now.Add(time.Minute)
+
+	//an unchanged status is not recorded
+	for _, state := range []JobState{CnstStatePending, CnstStateRunning, CnstStateRunning} {
+		if err = sq.SetJobStatus(ctx, "123456", state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	*now = now.Add(time.Minute)
+
+	req := &StopRequest{Mode: StopModeCancel, Reason: "superseded", RequestedBy: "ops@example.com"}
+	if err = sq.SetJobStop(ctx, "123456", CnstStateCancelling, req); err != nil {
+		t.Fatal(err)
+	}
+
+	hist, err := sq.GetJobHistory(ctx, "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []JobState{CnstStatePending, CnstStateRunning, CnstStateCancelling}
+	if len(hist) != len(want) {
+		t.Fatalf("expected %v, got %d rows", want, len(hist))
+	}
+
+	for i, h := range hist {
+		if h.Status != want[i] || h.AppScope != appscope || h.JobType != "testerjobtype" {
+			t.Fatalf("expected %s at %d, got %v", want[i], i, h)
+		}
+	}
+
+	if !hist[0].Observed.Equal(created) || !hist[2].Observed.Equal(*now) {
+		t.Fatalf("unexpected timestamps: %v %v", hist[0].Observed, hist[2].Observed)
+	}
+
+	if hist[1].StopMode != "" || hist[2].StopMode != req.Mode || hist[2].StopReason != req.Reason || hist[2].StoppedBy != req.RequestedBy {
+		t.Fatalf("expected only the stop row to record the request, got %v %v", hist[1], hist[2])
+	}
+
+	if _, err = sq.GetJobHistory(ctx, "654321"); err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+
+	//an imported job is marked on its first row only
+	err = sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "234567", JobType: "testerjobtype", LastStatus: CnstStatePending, Imported: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sq.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "234567", JobType: "testerjobtype", LastStatus: CnstStateRunning, Imported: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hist, err = sq.GetJobHistory(ctx, "234567")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hist) != 2 || !hist[0].Imported || hist[1].Imported {
+		t.Fatalf("expected only the first row to be imported, got %v %v", hist[0], hist[len(hist)-1])
+	}
+}
+
+func Test_SqliteAppScopeJobHistory(t *testing.T) {
+	ctx := context.Background()
+	sq, now := newTestSqliteMgr(ctx, t)
+	start := *now
+
+	//job 1 is recorded before the window and changes state inside it
+	seed := []*DsJob{
+		{AppScope: appscope, JobID: "1", JobType: "typea", LastStatus: CnstStatePending},
+		{AppScope: appscope, JobID: "2", JobType: "typea", LastStatus: CnstStatePending},
+		{AppScope: appscope, JobID: "3", JobType: "typeb", LastStatus: CnstStatePending},
+		{AppScope: "otherapp", JobID: "4", JobType: "typea", LastStatus: CnstStatePending},
+	}
+
+	for _, item := range seed {
+		if err := sq.SaveJob(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+		*now = now.Add(time.Hour)
+	}
+
+	if err := sq.SetJobStatus(ctx, "2", CnstStateRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sq.SetJobStatus(ctx, "1", CnstStateRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		jobtype string
+		want    []string
+	}{
+		{"", []string{"2", "3", "2"}},
+		{"typea", []string{"2", "2"}},
+	}
+
+	for _, tt := range tests {
+		hist, err := sq.GetAppScopeJobHistory(ctx, appscope, tt.jobtype, start.Add(time.Minute), *now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(hist) != len(tt.want) {
+			t.Fatalf("%q: expected %v, got %d rows", tt.jobtype, tt.want, len(hist))
+		}
+
+		for i, h := range hist {
+			if h.JobID != tt.want[i] {
+				t.Fatalf("%q: expected %v, got %s at %d", tt.jobtype, tt.want, h.JobID, i)
+			}
+		}
+	}
+
+	_, err := sq.GetAppScopeJobHistory(ctx, appscope, "typec", start, *now)
+	if err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+}
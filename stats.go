@@ -0,0 +1,149 @@
+package dfmgr
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+)
+
+// JobStats summarises the jobs of an appscope and jobtype which were first recorded within a window
+type JobStats struct {
+	AppScope string
+	JobType  string
+	From     time.Time
+	To       time.Time
+	//Jobs is the number of jobs first recorded within the window
+	Jobs int
+	//States counts the jobs by the last state recorded for them (jobs which are still active are counted under their current state)
+	States map[JobState]int
+	//FailureRate is the fraction of the finished jobs which failed
+	FailureRate float64
+	//RunTime is the time from first running to finishing, for jobs which completed successfully
+	RunTime DurationStats
+	//QueueTime is the time from the job being recorded to first running
+	QueueTime DurationStats
+}
+
+// DurationStats summarises a set of durations
+type DurationStats struct {
+	Count int
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+}
+
+// GetJobStats gets statistics for the jobs of an appscope (and jobtype, which can be empty string) which were first recorded within [from, to), one per jobtype
+func (dfm *DfMgr) GetJobStats(ctx context.Context, appscope, jobtype string, from, to time.Time) (_ []*JobStats, err error) {
+	op := beginOp(ctx, dfm.log, "GetJobStats", "appscope", appscope, "jobtype", jobtype, "from", from, "to", to)
+	defer op.end(&err)
+
+	if !to.After(from) {
+		return nil, ErrInvalidWindow
+	}
+
+	hist, err := dfm.ds.GetAppScopeJobHistory(ctx, appscope, jobtype, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return newJobStats(appscope, from, to, hist), nil
+}
+
+// newJobStats summarises the history of a set of jobs by jobtype, the history must be oldest first
+func newJobStats(appscope string, from, to time.Time, hist []*JobHistory) []*JobStats {
+	//split the history by job, keeping the order in which jobs were first seen
+	var order []string
+	byJob := make(map[string][]*JobHistory)
+	for _, h := range hist {
+		if _, ok := byJob[h.JobID]; !ok {
+			order = append(order, h.JobID)
+		}
+		byJob[h.JobID] = append(byJob[h.JobID], h)
+	}
+
+	type durations struct {
+		run   []time.Duration
+		queue []time.Duration
+	}
+
+	stats := make(map[string]*JobStats)
+	durs := make(map[string]*durations)
+
+	for _, jobID := range order {
+		tl := newJobTimeline(byJob[jobID])
+
+		st, ok := stats[tl.JobType]
+		if !ok {
+			st = &JobStats{AppScope: appscope, JobType: tl.JobType, From: from, To: to, States: make(map[JobState]int)}
+			stats[tl.JobType] = st
+			durs[tl.JobType] = &durations{}
+		}
+
+		st.Jobs++
+		st.States[tl.FinalState]++
+
+		if d, ok := tl.QueueTime(); ok {
+			durs[tl.JobType].queue = append(durs[tl.JobType].queue, d)
+		}
+
+		if d, ok := tl.RunTime(); ok && tl.FinalState == CnstStateDone {
+			durs[tl.JobType].run = append(durs[tl.JobType].run, d)
+		}
+	}
+
+	result := make([]*JobStats, 0, len(stats))
+	for jt, st := range stats {
+		var finished int
+		for state, ct := range st.States {
+			if state.IsTerminal() {
+				finished += ct
+			}
+		}
+
+		if finished > 0 {
+			st.FailureRate = float64(st.States[CnstStateFailed]) / float64(finished)
+		}
+
+		st.RunTime = newDurationStats(durs[jt].run)
+		st.QueueTime = newDurationStats(durs[jt].queue)
+
+		result = append(result, st)
+	}
+
+	slices.SortFunc(result, func(a, b *JobStats) int { return cmp.Compare(a.JobType, b.JobType) })
+
+	return result
+}
+
+// newDurationStats summarises a set of durations, using the nearest rank for the percentiles
+func newDurationStats(ds []time.Duration) DurationStats {
+	if len(ds) == 0 {
+		return DurationStats{}
+	}
+
+	slices.Sort(ds)
+
+	var total time.Duration
+	for _, d := range ds {
+		total += d
+	}
+
+	return DurationStats{
+		Count: len(ds),
+		Mean:  total / time.Duration(len(ds)),
+		P50:   percentile(ds, 0.5),
+		P95:   percentile(ds, 0.95),
+	}
+}
+
+// percentile returns the nearest rank percentile of a sorted, non-empty set of durations
+func percentile(ds []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(ds))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return ds[rank-1]
+}
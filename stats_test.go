@@ -0,0 +1,151 @@
+package dfmgr
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_GetJobStats(t *testing.T) {
+	ctx := context.Background()
+	dfm, _, mm := newFakeMgr(ctx, t)
+
+	start := time.Date(2019, 4, 11, 12, 0, 0, 0, time.UTC)
+	now := start
+	mm.now = func() time.Time { return now }
+
+	//each job is recorded, queued for queue minutes, then runs for run minutes before reaching its final state
+	seed := []struct {
+		jobid   string
+		jobtype string
+		queue   int
+		run     int
+		final   JobState
+	}{
+		{"1", "typea", 1, 10, CnstStateDone},
+		{"2", "typea", 2, 20, CnstStateDone},
+		{"3", "typea", 3, 30, CnstStateDone},
+		{"4", "typea", 4, 40, CnstStateFailed},
+		{"5", "typea", 5, 0, CnstStateRunning},
+		{"6", "typeb", 1, 5, CnstStateCancelled},
+	}
+
+	for _, item := range seed {
+		now = start
+		if err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: item.jobid, JobType: item.jobtype, LastStatus: CnstStatePending}); err != nil {
+			t.Fatal(err)
+		}
+
+		now = now.Add(time.Duration(item.queue) * time.Minute)
+		if err := mm.SetJobStatus(ctx, item.jobid, CnstStateRunning); err != nil {
+			t.Fatal(err)
+		}
+
+		now = now.Add(time.Duration(item.run) * time.Minute)
+		if err := mm.SetJobStatus(ctx, item.jobid, item.final); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//a job recorded after the window is excluded
+	now = start.Add(2 * time.Hour)
+	if err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "7", JobType: "typea", LastStatus: CnstStateFailed}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := dfm.GetJobStats(ctx, appscope, "", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stats) != 2 || stats[0].JobType != "typea" || stats[1].JobType != "typeb" {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+
+	st := stats[0]
+	if st.Jobs != 5 || st.States[CnstStateDone] != 3 || st.States[CnstStateFailed] != 1 || st.States[CnstStateRunning] != 1 {
+		t.Fatalf("unexpected counts %v", st)
+	}
+
+	if st.FailureRate != 0.25 {
+		t.Fatalf("expected a failure rate of 0.25, got %v", st.FailureRate)
+	}
+
+	//only successful runs are timed
+	want := DurationStats{Count: 3, Mean: 20 * time.Minute, P50: 20 * time.Minute, P95: 30 * time.Minute}
+	if st.RunTime != want {
+		t.Fatalf("expected run time %v, got %v", want, st.RunTime)
+	}
+
+	want = DurationStats{Count: 5, Mean: 3 * time.Minute, P50: 3 * time.Minute, P95: 5 * time.Minute}
+	if st.QueueTime != want {
+		t.Fatalf("expected queue time %v, got %v", want, st.QueueTime)
+	}
+
+	if stats[1].FailureRate != 0 || stats[1].RunTime.Count != 0 || stats[1].States[CnstStateCancelled] != 1 {
+		t.Fatalf("unexpected stats %v", stats[1])
+	}
+
+	stats, err = dfm.GetJobStats(ctx, appscope, "typeb", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stats) != 1 || stats[0].JobType != "typeb" {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+
+	if _, err = dfm.GetJobStats(ctx, appscope, "", start.Add(3*time.Hour), start.Add(4*time.Hour)); err != ErrNoDataFound {
+		t.Fatalf("expected ErrNoDataFound, got %v", err)
+	}
+
+	if _, err = dfm.GetJobStats(ctx, appscope, "", start, start); err != ErrInvalidWindow {
+		t.Fatalf("expected ErrInvalidWindow, got %v", err)
+	}
+}
+
+func Test_GetJobStatsArchived(t *testing.T) {
+	ctx := context.Background()
+	dfm, _, mm := newFakeMgr(ctx, t)
+
+	start := time.Date(2019, 4, 11, 12, 0, 0, 0, time.UTC)
+	now := start
+	mm.now = func() time.Time { return now }
+
+	if err := mm.SaveJob(ctx, &DsJob{AppScope: appscope, JobID: "1", JobType: "typea", LastStatus: CnstStatePending}); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := mm.SetJobStatus(ctx, "1", CnstStateRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if err := mm.SetJobStatus(ctx, "1", CnstStateDone); err != nil {
+		t.Fatal(err)
+	}
+
+	//the job is archived, but its history is kept
+	now = start.Add(25 * time.Hour)
+	if err := mm.DeleteJobArchive(ctx, appscope); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mm.GetJob(ctx, "1"); err != ErrNoDataFound {
+		t.Fatalf("expected the job to be archived, got %v", err)
+	}
+
+	stats, err := dfm.GetJobStats(ctx, appscope, "", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stats) != 1 || stats[0].Jobs != 1 || stats[0].States[CnstStateDone] != 1 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+
+	if stats[0].RunTime.Mean != 10*time.Minute || stats[0].QueueTime.Mean != 2*time.Minute {
+		t.Fatalf("unexpected durations %v %v", stats[0].RunTime, stats[0].QueueTime)
+	}
+}
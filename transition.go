@@ -0,0 +1,42 @@
+package dfmgr
+
+import "fmt"
+
+// stopTransitions lists the states a stop may be requested from (through UpdateJob), keyed by the requested state
+//
+// The table follows the CnstState* documentation: a job may be cancelled until it has reached a terminal state (a job
+// which is already cancelling is excluded), and may only be drained while it is running.
+var stopTransitions = map[JobState]map[JobState]bool{
+	CnstStateCancelled: {
+		CnstStateQueued:   true,
+		CnstStatePending:  true,
+		CnstStateStopped:  true,
+		CnstStateRunning:  true,
+		CnstStateDraining: true,
+	},
+	CnstStateDrained: {
+		CnstStateRunning: true,
+	},
+}
+
+// canRequestState reports whether a job in state from may be asked to move to the requested state
+func canRequestState(from, requested JobState) bool {
+	return stopTransitions[requested][from]
+}
+
+// TransitionError occurs if a job is asked to move to a state which it cannot reach from its current state
+type TransitionError struct {
+	JobID     string
+	From      JobState
+	Requested JobState
+}
+
+// Error returns the error message
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("job %s cannot move from %s to %s", e.JobID, e.From, e.Requested)
+}
+
+// Unwrap allows errors.Is(err, ErrInvalidTransition)
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
@@ -0,0 +1,80 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_CanRequestState(t *testing.T) {
+	tests := []struct {
+		from      JobState
+		requested JobState
+		want      bool
+	}{
+		{CnstStateQueued, CnstStateCancelled, true},
+		{CnstStatePending, CnstStateCancelled, true},
+		{CnstStateStopped, CnstStateCancelled, true},
+		{CnstStateRunning, CnstStateCancelled, true},
+		{CnstStateDraining, CnstStateCancelled, true},
+		{CnstStateCancelling, CnstStateCancelled, false},
+		{CnstStateDone, CnstStateCancelled, false},
+		{CnstStateUnknown, CnstStateCancelled, false},
+		{CnstStateRunning, CnstStateDrained, true},
+		{CnstStatePending, CnstStateDrained, false},
+		{CnstStateDraining, CnstStateDrained, false},
+		{CnstStateRunning, CnstStateDone, false},
+	}
+
+	for _, tt := range tests {
+		if got := canRequestState(tt.from, tt.requested); got != tt.want {
+			t.Fatalf("expected %v for %s to %s, got %v", tt.want, tt.from, tt.requested, got)
+		}
+	}
+}
+func Test_JobStopStates(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, _ := newFakeMgr(ctx, t)
+
+	//queued, pending and draining jobs can be cancelled
+	for _, state := range []JobState{CnstStateQueued, CnstStatePending, CnstStateDraining} {
+		fc.Script(state)
+
+		meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		jb, err := dfm.JobStop(ctx, meta.JobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if jb.CurrentState != CnstStateCancelling {
+			t.Fatalf("expected %s from %s, got %s", CnstStateCancelling, state, jb.CurrentState)
+		}
+
+		//a job which is already cancelling cannot be cancelled again
+		var terr *TransitionError
+		_, err = dfm.JobStop(ctx, meta.JobID)
+		if !errors.As(err, &terr) || !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("expected a *TransitionError, got %v", err)
+		}
+
+		if terr.JobID != meta.JobID || terr.Requested != CnstStateCancelled {
+			t.Fatalf("unexpected error %v", terr)
+		}
+	}
+
+	//pending jobs cannot be drained
+	fc.Script(CnstStatePending)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dfm.JobDrain(ctx, meta.JobID); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTransition, err)
+	}
+}
@@ -0,0 +1,88 @@
+package dfmgr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Validate checks a legacy job definition before it is launched in region (which is not checked if empty), returning a
+// *DefinitionError which lists every problem under its legacy field name. The checks are those of JobDefinition.Validate, after
+// the runtimeenvironment keys have been checked to map to runtime settings with parseable values.
+func (jp *JobRunParameter) Validate(region string) error {
+	if jp == nil {
+		return ErrNoJobDefinition
+	}
+
+	de := &DefinitionError{}
+
+	rs := applyRuntimeSettings(jp.RuntimeEnvironment, func(key string, err error) {
+		if errors.Is(err, ErrUnknownRuntimeKey) {
+			de.add("environment."+key, "is not a runtime setting")
+			return
+		}
+
+		//report the reason rather than the strconv call
+		var ne *strconv.NumError
+		if errors.As(err, &ne) {
+			err = ne.Err
+		}
+
+		de.add("environment."+key, fmt.Sprintf("value %q is invalid: %v", jp.RuntimeEnvironment[key], err))
+	})
+
+	jp.definition(rs).validate(region, de)
+
+	if len(de.Problems) == 0 {
+		return nil
+	}
+
+	for i := range de.Problems {
+		de.Problems[i].Field = jp.legacyField(de.Problems[i].Field)
+	}
+
+	return de
+}
+
+// legacyField converts a typed job definition field name to its name in the legacy format
+func (jp *JobRunParameter) legacyField(field string) string {
+	switch field {
+	case "jobname":
+		return "jobrequest.jobName"
+	case "location":
+		return "jobrequest.location"
+	case "templatepath":
+		return "jobrequest." + jp.templatePathKey()
+	}
+
+	if key, ok := strings.CutPrefix(field, "environment."); ok {
+		return "runtimeenvironment." + key
+	}
+
+	return field
+}
+
+// ValidateJobDefinition checks a legacy job definition against the manager's region (see JobRunParameter.Validate)
+func (dfm *DfMgr) ValidateJobDefinition(jobParam *JobRunParameter) error {
+	return jobParam.Validate(dfm.region)
+}
+
+// jobNameProblem describes what is wrong with a job name format, empty if it is valid
+func jobNameProblem(name string) string {
+	if strings.Count(name, "%s") != 1 || strings.Count(name, "%") != 1 {
+		return "must contain exactly one %s"
+	}
+
+	return ""
+}
+
+// gcsURIProblem describes what is wrong with a gcs object path, empty if it is valid
+func gcsURIProblem(path string) string {
+	bucket, object, ok := strings.Cut(strings.TrimPrefix(path, "gs://"), "/")
+	if !strings.HasPrefix(path, "gs://") || !ok || bucket == "" || object == "" {
+		return "must be a gs://bucket/object uri"
+	}
+
+	return ""
+}
@@ -0,0 +1,107 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_ValidateJobDefinition(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, _ := newFakeMgr(ctx, t)
+
+	for _, param := range []*JobRunParameter{newTestJobParam(), newTestFlexJobParam()} {
+		if err := dfm.ValidateJobDefinition(param); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	param := newTestJobParam()
+	param.JobRequest["jobName"] = "dflauncher"
+	param.JobRequest["gcsPath"] = "/dataflow/templates/test"
+	param.JobRequest["location"] = "us-central1"
+	param.RuntimeEnvironment["maxWorkers"] = "2"
+	param.RuntimeEnvironment["numWorkers"] = "3"
+	param.RuntimeEnvironment["diskSizeGb"] = "10"
+	param.RuntimeEnvironment["enableStreamingEngine"] = "yes please"
+
+	err := dfm.ValidateJobDefinition(param)
+
+	var de *DefinitionError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected a *DefinitionError, got %v", err)
+	}
+
+	//every problem is reported at once
+	want := []string{
+		"runtimeenvironment.diskSizeGb",
+		"runtimeenvironment.enableStreamingEngine",
+		"jobrequest.jobName",
+		"jobrequest.gcsPath",
+		"jobrequest.location",
+		"runtimeenvironment.numWorkers",
+	}
+
+	if len(de.Problems) != len(want) {
+		t.Fatalf("expected problems with %v, got %v", want, de.Problems)
+	}
+
+	for i, item := range de.Problems {
+		if item.Field != want[i] {
+			t.Fatalf("expected %s at %d, got %s", want[i], i, item.Field)
+		}
+	}
+
+	if de.Problems[1].Reason != `value "yes please" is invalid: invalid syntax` {
+		t.Fatalf("unexpected reason %q", de.Problems[1].Reason)
+	}
+
+	//definitions for another region are not launched
+	param = newTestJobParam()
+	param.JobRequest["location"] = "us-central1"
+
+	if _, err = dfm.JobStart(ctx, jbappscope, jobtype, param); !errors.As(err, &de) || de.Problems[0].Field != "location" {
+		t.Fatalf("expected a location problem, got %v", err)
+	}
+
+	if len(fc.Launched()) != 0 {
+		t.Fatal("expected no launches")
+	}
+
+	tests := []struct {
+		jobname string
+		path    string
+		want    int
+	}{
+		{"dflauncher%s", "gs://testproject/dataflow/templates/test", 0},
+		{"dflauncher%s%s", "gs://testproject/dataflow/templates/test", 1},
+		{"dflauncher%d", "gs://testproject", 2},
+		{"dflauncher%s-%d", "gs:///test", 2},
+		{"", "", 2},
+	}
+
+	for _, tt := range tests {
+		param = newTestJobParam()
+		param.JobRequest["jobName"] = tt.jobname
+		param.JobRequest["gcsPath"] = tt.path
+
+		err = param.Validate("")
+
+		var got int
+		if errors.As(err, &de) {
+			got = len(de.Problems)
+		}
+
+		if got != tt.want {
+			t.Fatalf("expected %d problems for %q %q, got %v", tt.want, tt.jobname, tt.path, err)
+		}
+	}
+
+	//the flex template path is checked instead of gcsPath
+	param = newTestFlexJobParam()
+	param.JobRequest["containerSpecGcsPath"] = "dataflow/flextemplates/test.json"
+
+	if err = param.Validate("europe-west1"); !errors.As(err, &de) || de.Problems[0].Field != "jobrequest.containerSpecGcsPath" {
+		t.Fatalf("expected a containerSpecGcsPath problem, got %v", err)
+	}
+}
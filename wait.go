@@ -0,0 +1,132 @@
+package dfmgr
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	df "google.golang.org/api/dataflow/v1b3"
+)
+
+// WaitOptions controls how often WaitForJob polls a job, zero values use the defaults
+type WaitOptions struct {
+	//Interval is the delay before the second poll (default 10s)
+	Interval time.Duration
+	//MaxInterval caps the delay between polls (default 2m)
+	MaxInterval time.Duration
+	//Multiplier grows the delay after each poll, 1 polls at a fixed interval (default 2)
+	Multiplier float64
+	//Jitter randomises each delay by up to this fraction of it, between 0 and 1 (default 0, no jitter)
+	Jitter float64
+}
+
+// wait option defaults
+const (
+	defaultWaitInterval    = 10 * time.Second
+	defaultWaitMaxInterval = 2 * time.Minute
+	defaultWaitMultiplier  = 2
+)
+
+// withDefaults returns a copy of the options with any unset values defaulted
+func (wo *WaitOptions) withDefaults() WaitOptions {
+	var o WaitOptions
+	if wo != nil {
+		o = *wo
+	}
+
+	if o.Interval <= 0 {
+		o.Interval = defaultWaitInterval
+	}
+
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = defaultWaitMaxInterval
+	}
+
+	if o.MaxInterval < o.Interval {
+		o.MaxInterval = o.Interval
+	}
+
+	if o.Multiplier < 1 {
+		o.Multiplier = defaultWaitMultiplier
+	}
+
+	if o.Jitter < 0 {
+		o.Jitter = 0
+	}
+
+	if o.Jitter > 1 {
+		o.Jitter = 1
+	}
+
+	return o
+}
+
+// next returns the backed off interval which follows d
+func (wo WaitOptions) next(d time.Duration) time.Duration {
+	n := time.Duration(float64(d) * wo.Multiplier)
+	if n > wo.MaxInterval || n < d {
+		return wo.MaxInterval
+	}
+
+	return n
+}
+
+// delay returns d randomised by the jitter fraction
+func (wo WaitOptions) delay(d time.Duration) time.Duration {
+	if wo.Jitter == 0 {
+		return d
+	}
+
+	return d + time.Duration(float64(d)*wo.Jitter*(2*rand.Float64()-1))
+}
+
+// WaitForJob polls a job until it reaches a terminal state (done, failed, cancelled, updated or drained) or the context ends,
+// updating the job status record each time the state changes. wo may be nil to use the default polling options.
+func (dfm *DfMgr) WaitForJob(ctx context.Context, jobID string, wo *WaitOptions) (_ *df.Job, err error) {
+	op := beginOp(ctx, dfm.log, "WaitForJob", "jobid", jobID)
+	defer op.end(&err)
+
+	o := wo.withDefaults()
+	interval := o.Interval
+
+	var last JobState
+
+	for {
+		jb, err := dfm.dfc.GetJob(ctx, dfm.project, dfm.region, jobID)
+		if err != nil {
+			return nil, err
+		}
+
+		state, err := ParseJobState(jb.CurrentState)
+		if err != nil {
+			return nil, err
+		}
+
+		//only record transitions
+		if state != last {
+			err = dfm.ds.SetJobStatus(ctx, jobID, state)
+			if err != nil {
+				return nil, err
+			}
+
+			op.log.DebugContext(ctx, "transition", "from", last, "jobstate", state)
+			last = state
+		}
+
+		if state.IsTerminal() {
+			op.add("jobstate", state)
+			return jb, nil
+		}
+
+		tm := time.NewTimer(o.delay(interval))
+
+		select {
+		case <-ctx.Done():
+			tm.Stop()
+			return nil, ctx.Err()
+		case <-tm.C:
+		}
+
+		interval = o.next(interval)
+	}
+}
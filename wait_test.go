@@ -0,0 +1,118 @@
+package dfmgr
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+//countingStore records the status updates made against a job store
+type countingStore struct {
+	JobStore
+	states []JobState
+}
+
+func (cs *countingStore) SetJobStatus(ctx context.Context, jobid string, jobstate JobState) error {
+	cs.states = append(cs.states, jobstate)
+	return cs.JobStore.SetJobStatus(ctx, jobid, jobstate)
+}
+
+func Test_WaitForJob(t *testing.T) {
+	ctx := context.Background()
+
+	fc := NewFakeDataflowClient()
+	fc.Script(CnstStatePending, CnstStatePending, CnstStateRunning, CnstStateRunning, CnstStateRunning, CnstStateDone)
+
+	cs := &countingStore{JobStore: NewMemMgr(ctx)}
+
+	dfm, err := NewMgrWithOptions(ctx,
+		WithDataflowClient(fc),
+		WithJobStore(cs),
+		WithProject("testproject"),
+		WithRegion("europe-west1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jb, err := dfm.WaitForJob(ctx, meta.JobID, &WaitOptions{Interval: time.Millisecond, Jitter: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jb.CurrentState != CnstStateDone {
+		t.Fatalf("expected %s, got %s", CnstStateDone, jb.CurrentState)
+	}
+
+	//only the transitions are written
+	want := []JobState{CnstStatePending, CnstStateRunning, CnstStateDone}
+	if len(cs.states) != len(want) {
+		t.Fatalf("expected %v, got %v", want, cs.states)
+	}
+
+	for i := range want {
+		if cs.states[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, cs.states)
+		}
+	}
+
+	ds, err := dfm.GetJob(ctx, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.LastStatus != CnstStateDone {
+		t.Fatalf("expected %s, got %s", CnstStateDone, ds.LastStatus)
+	}
+}
+func Test_WaitForJobContext(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, _ := newFakeMgr(ctx, t)
+	fc.Script(CnstStateRunning)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	_, err = dfm.WaitForJob(tctx, meta.JobID, &WaitOptions{Interval: time.Millisecond, MaxInterval: 5 * time.Millisecond})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+func Test_WaitOptions(t *testing.T) {
+	o := (*WaitOptions)(nil).withDefaults()
+	if o.Interval != defaultWaitInterval || o.MaxInterval != defaultWaitMaxInterval || o.Multiplier != defaultWaitMultiplier || o.Jitter != 0 {
+		t.Fatalf("unexpected defaults %v", o)
+	}
+
+	//backoff is capped
+	o = (&WaitOptions{Interval: time.Second, MaxInterval: 5 * time.Second, Multiplier: 3}).withDefaults()
+
+	want := []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second}
+	d := o.Interval
+
+	for i := range want {
+		if d != want[i] {
+			t.Fatalf("expected %v at %d, got %v", want[i], i, d)
+		}
+		d = o.next(d)
+	}
+
+	//jitter stays within its fraction of the interval
+	o = (&WaitOptions{Interval: time.Second, Jitter: 0.2}).withDefaults()
+
+	for i := 0; i < 100; i++ {
+		if d = o.delay(time.Second); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range", d)
+		}
+	}
+}
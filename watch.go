@@ -0,0 +1,162 @@
+package dfmgr
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	df "google.golang.org/api/dataflow/v1b3"
+)
+
+// JobEvent is a change in the state of a watched job, or a failure to read it
+type JobEvent struct {
+	JobID string
+	//From is the state last recorded in the job store (empty if the job is not in the store)
+	From JobState
+	//To is the job's current state
+	To JobState
+	//Job is the job as read from dataflow
+	Job *df.Job
+	//Err is set if the job could not be read or its status could not be recorded, the watch carries on polling unless the job no longer exists
+	Err error
+}
+
+// watchedJob is the last recorded state of a watched job
+type watchedJob struct {
+	id    string
+	state JobState
+	done  bool
+}
+
+// Watch polls the jobs and sends an event each time a job's state differs from the LastStatus recorded in the job store,
+// recording the new state before sending. The channel is closed once every job has reached a terminal state (or no longer exists in
+// dataflow) or the context ends.
+// wo controls the polling interval and backoff (the interval resets whenever a job changes state), and may be nil to use the defaults.
+func (dfm *DfMgr) Watch(ctx context.Context, wo *WaitOptions, jobIDs ...string) (<-chan JobEvent, error) {
+	if len(jobIDs) == 0 {
+		return nil, ErrNoJobIDs
+	}
+
+	jbs := make([]*watchedJob, 0, len(jobIDs))
+
+	for _, id := range jobIDs {
+		wj := &watchedJob{id: id}
+
+		ds, err := dfm.ds.GetJob(ctx, id)
+		switch {
+		case errors.Is(err, ErrNoDataFound):
+		case err != nil:
+			return nil, err
+		default:
+			wj.state = ds.LastStatus
+		}
+
+		jbs = append(jbs, wj)
+	}
+
+	ch := make(chan JobEvent)
+
+	go dfm.watch(ctx, wo.withDefaults(), jbs, ch)
+
+	return ch, nil
+}
+
+// watch runs the polling loop for Watch
+func (dfm *DfMgr) watch(ctx context.Context, o WaitOptions, jbs []*watchedJob, ch chan<- JobEvent) {
+	defer close(ch)
+
+	op := beginOp(ctx, dfm.log, "Watch", "jobs", len(jbs))
+
+	var err error
+	defer op.end(&err)
+
+	interval := o.Interval
+
+	for {
+		changed, active := false, 0
+
+		for _, wj := range jbs {
+			if wj.done {
+				continue
+			}
+
+			ev, ok := dfm.pollWatchedJob(ctx, wj)
+			if ok {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					err = watchErr(ctx)
+					return
+				}
+
+				changed = changed || ev.Err == nil
+			}
+
+			if !wj.done {
+				active++
+			}
+		}
+
+		if active == 0 {
+			return
+		}
+
+		if changed {
+			interval = o.Interval
+		}
+
+		tm := time.NewTimer(o.delay(interval))
+
+		select {
+		case <-ctx.Done():
+			tm.Stop()
+			err = watchErr(ctx)
+			return
+		case <-tm.C:
+		}
+
+		interval = o.next(interval)
+	}
+}
+
+// pollWatchedJob reads a watched job, recording and returning an event if its state has changed
+func (dfm *DfMgr) pollWatchedJob(ctx context.Context, wj *watchedJob) (JobEvent, bool) {
+	jb, err := dfm.dfc.GetJob(ctx, dfm.project, dfm.region, wj.id)
+	if err != nil {
+		//a job which has been deleted will never change state again
+		wj.done = isNotFound(err)
+		return JobEvent{JobID: wj.id, From: wj.state, Err: err}, true
+	}
+
+	state, err := ParseJobState(jb.CurrentState)
+	if err != nil {
+		return JobEvent{JobID: wj.id, From: wj.state, Job: jb, Err: err}, true
+	}
+
+	if state == wj.state {
+		wj.done = state.IsTerminal()
+		return JobEvent{}, false
+	}
+
+	ev := JobEvent{JobID: wj.id, From: wj.state, To: state, Job: jb}
+
+	err = dfm.ds.SetJobStatus(ctx, wj.id, state)
+	if err != nil {
+		ev.Err = err
+		return ev, true
+	}
+
+	wj.state = state
+	wj.done = state.IsTerminal()
+
+	return ev, true
+}
+
+// watchErr returns the reason a watch ended early, cancelling the context is the normal way to stop a watch so is not an error
+func watchErr(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil
+	}
+
+	return ctx.Err()
+}
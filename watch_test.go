@@ -0,0 +1,161 @@
+package dfmgr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Watch(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, mm := newFakeMgr(ctx, t)
+	fc.Script(CnstStatePending, CnstStatePending, CnstStateRunning, CnstStateDone)
+
+	var ids []string
+
+	for i := 0; i < 2; i++ {
+		meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, meta.JobID)
+	}
+
+	if err := fc.ScriptJob(ids[1], CnstStateRunning, CnstStateRunning, CnstStateFailed); err != nil {
+		t.Fatal(err)
+	}
+
+	errAPI := errors.New("api unavailable")
+	fc.FailNext("GetJob", errAPI)
+
+	tctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ch, err := dfm.Watch(tctx, &WaitOptions{Interval: time.Millisecond}, ids...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]JobState)
+	var errs int
+
+	for ev := range ch {
+		if ev.Err != nil {
+			if ev.Err != errAPI {
+				t.Fatal(ev.Err)
+			}
+			errs++
+			continue
+		}
+
+		got[ev.JobID] = append(got[ev.JobID], ev.From+">"+ev.To)
+	}
+
+	if tctx.Err() != nil {
+		t.Fatal("expected the watch to end once the jobs were terminal")
+	}
+
+	if errs != 1 {
+		t.Fatalf("expected 1 error event, got %d", errs)
+	}
+
+	//only actual transitions from the stored status are sent
+	want := map[string][]JobState{
+		ids[0]: {CnstStatePending + ">" + CnstStateRunning, CnstStateRunning + ">" + CnstStateDone},
+		ids[1]: {CnstStatePending + ">" + CnstStateRunning, CnstStateRunning + ">" + CnstStateFailed},
+	}
+
+	for id, w := range want {
+		if len(got[id]) != len(w) {
+			t.Fatalf("expected %v for %s, got %v", w, id, got[id])
+		}
+
+		for i := range w {
+			if got[id][i] != w[i] {
+				t.Fatalf("expected %v for %s, got %v", w, id, got[id])
+			}
+		}
+	}
+
+	ds, err := mm.GetJob(ctx, ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.LastStatus != CnstStateFailed {
+		t.Fatalf("expected %s, got %s", CnstStateFailed, ds.LastStatus)
+	}
+}
+func Test_WatchContext(t *testing.T) {
+	ctx := context.Background()
+	dfm, fc, _ := newFakeMgr(ctx, t)
+
+	if _, err := dfm.Watch(ctx, nil); err != ErrNoJobIDs {
+		t.Fatalf("expected %v, got %v", ErrNoJobIDs, err)
+	}
+
+	fc.Script(CnstStateRunning)
+
+	meta, err := dfm.JobStart(ctx, jbappscope, jobtype, newTestJobParam())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	dfm.log = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	tctx, cancel := context.WithCancel(ctx)
+
+	ch, err := dfm.Watch(tctx, &WaitOptions{Interval: time.Millisecond}, meta.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	//a running job sends no events, and the channel is closed when the context ends
+	for ev := range ch {
+		t.Fatalf("unexpected event %v", ev)
+	}
+
+	//cancelling is the normal way to stop a watch, so is not logged as a failure
+	if strings.Contains(buf.String(), "level=ERROR") || !strings.Contains(buf.String(), "op=Watch") {
+		t.Fatalf("expected a clean end, got %q", buf.String())
+	}
+}
+func Test_WatchMissingJob(t *testing.T) {
+	ctx := context.Background()
+	dfm, _, mm := newFakeMgr(ctx, t)
+
+	//a job which is recorded in the store but no longer exists in dataflow
+	err := mm.SaveJob(ctx, &DsJob{AppScope: jbappscope, JobID: "deletedjob", JobType: jobtype, LastStatus: CnstStateRunning})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ch, err := dfm.Watch(tctx, &WaitOptions{Interval: time.Millisecond}, "deletedjob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var evs []JobEvent
+	for ev := range ch {
+		evs = append(evs, ev)
+	}
+
+	if tctx.Err() != nil {
+		t.Fatal("expected the watch to end once the job was found to be missing")
+	}
+
+	if len(evs) != 1 || !isNotFound(evs[0].Err) {
+		t.Fatalf("expected a single not found event, got %v", evs)
+	}
+}